@@ -6,6 +6,7 @@ package config
 
 import (
 	"fmt"
+	"io"
 	"net/mail"
 	"os"
 	"path/filepath"
@@ -13,6 +14,7 @@ import (
 	"strings"
 	"time"
 
+	services "github.com/vinialx/vloggo-go/internal"
 	types "github.com/vinialx/vloggo-go/types"
 
 	"github.com/joho/godotenv"
@@ -163,9 +165,29 @@ func DefaultSMTP(client string) (bool, types.VLoggoSMTP) {
 	return true, smtp
 }
 
+// DefaultLevel reads the minimum log level from the LOG_LEVEL environment variable.
+// It returns types.Debug (log everything) when the variable is empty or invalid.
+func DefaultLevel(client string) types.LogLevel {
+	value := strings.ToUpper(strings.TrimSpace(os.Getenv("LOG_LEVEL")))
+
+	switch level := types.LogLevel(value); level {
+	case types.Debug, types.Info, types.Warn, types.Error, types.Fatal:
+		return level
+	case "":
+		return types.Debug
+	default:
+		fmt.Printf("[VLoggo] > [%s] [%s] [ERROR] : invalid log level %s\n",
+			client,
+			Date(),
+			value,
+		)
+		return types.Debug
+	}
+}
+
 // DefaultConfig creates and returns a VLoggoConfig struct populated with
-// default values. It calls DefaultSMTP and DefaultDirectory to set
-// the default SMTP and path settings.
+// default values. It calls DefaultSMTP, DefaultLevel and DefaultDirectory to set
+// the default SMTP, minimum level and path settings.
 func DefaultConfig() types.VLoggoConfig {
 	notify, smtp := DefaultSMTP("VLoggo")
 
@@ -173,11 +195,21 @@ func DefaultConfig() types.VLoggoConfig {
 		Client:    "VLoggo",
 		Json:      false,
 		Notify:    notify,
+		Digest:    false,
 		Debug:     true,
 		Console:   true,
+		MinLevel:  DefaultLevel("VLoggo"),
+		Levels:    types.Levels{Console: types.Debug, Txt: types.Debug, Json: types.Debug},
 		Throttle:  30,
+		MaxSize:   0,
+		Compress:  false,
 		Filecount: types.Count{Txt: 31, Json: 31},
+		Retention: types.Retention{MaxAge: 0, MaxTotalBytes: 0},
+		Rollover:  types.Rollover{Interval: types.Daily, Location: time.Local, Timer: false},
+		Flush:     types.Flush{Size: 32 * 1024, Interval: time.Second, Level: types.Error},
+		Async:     types.Async{Enabled: false, Queue: 1024, Overflow: types.Block},
 		Directory: DefaultDirectory("VLoggo"),
+		Templates: types.Templates{Txt: "log-{date}.txt", Json: "log-{date}.jsonl", Current: false},
 		SMTP:      smtp,
 	}
 }
@@ -198,6 +230,14 @@ func WithJSON(cfg types.VLoggoConfig, enabled bool) Option {
 	}
 }
 
+// WithDigest returns an Option function that sets the Digest (enabled) field
+// of a VLoggoConfig.
+func WithDigest(cfg types.VLoggoConfig, enabled bool) Option {
+	return func(cfg *types.VLoggoConfig) {
+		cfg.Digest = enabled
+	}
+}
+
 // WithDebug returns an Option function that sets the Debug (enabled) field
 // of a VLoggoConfig.
 func WithDebug(cfg types.VLoggoConfig, enabled bool) Option {
@@ -214,6 +254,163 @@ func WithConsole(cfg types.VLoggoConfig, enabled bool) Option {
 	}
 }
 
+// WithLevel returns an Option function that sets the MinLevel field
+// of a VLoggoConfig. Entries below this level are discarded.
+func WithLevel(cfg types.VLoggoConfig, level types.LogLevel) Option {
+	return func(cfg *types.VLoggoConfig) {
+		cfg.MinLevel = level
+	}
+}
+
+// WithLevels returns an Option function that sets the Levels (console/txt/json) field
+// of a VLoggoConfig, the minimum level of each built-in sink.
+func WithLevels(cfg types.VLoggoConfig, levels types.Levels) Option {
+	return func(cfg *types.VLoggoConfig) {
+		cfg.Levels = levels
+	}
+}
+
+// addOutput appends output to outputs, replacing the output with the same non-empty Key
+// outputs is copied, so configs sharing its backing array are left untouched
+func addOutput(outputs []types.Output, output types.Output) []types.Output {
+	result := make([]types.Output, 0, len(outputs)+1)
+
+	replaced := false
+	for _, existing := range outputs {
+		if output.Key != "" && existing.Key == output.Key {
+			existing, replaced = output, true
+		}
+		result = append(result, existing)
+	}
+
+	if !replaced {
+		result = append(result, output)
+	}
+
+	return result
+}
+
+// minLevel returns the first level provided, types.Debug if there is none
+func minLevel(level []types.LogLevel) types.LogLevel {
+	if len(level) > 0 {
+		return level[0]
+	}
+
+	return types.Debug
+}
+
+// WithSink returns an Option function that appends a sink to the Outputs field
+// of a VLoggoConfig. The sink receives entries at or above level.
+// The sink is closed by the instance the option is applied to. Instances cloned from it
+// write to the same sink without closing it.
+func WithSink(cfg types.VLoggoConfig, sink types.Sink, level types.LogLevel) Option {
+	return func(cfg *types.VLoggoConfig) {
+		cfg.Outputs = addOutput(cfg.Outputs, types.Output{Sink: sink, Level: level})
+	}
+}
+
+// WithWriter returns an Option function that appends a sink writing to w to the Outputs field
+// of a VLoggoConfig. Lines are written as JSON when format is types.JSON, as text otherwise.
+// The sink receives entries at or above level, every entry if level is not provided.
+// w is never closed by VLoggo.
+func WithWriter(cfg types.VLoggoConfig, w io.Writer, format types.Format, level ...types.LogLevel) Option {
+	return func(cfg *types.VLoggoConfig) {
+		cfg.Outputs = addOutput(cfg.Outputs, types.Output{
+			New: func(cfg types.VLoggoConfig) types.Sink {
+				return services.NewWriterSink(w, format, cfg.Client)
+			},
+			Level: minLevel(level),
+		})
+	}
+}
+
+// WithSyslog returns an Option function that appends a syslog sink to the Outputs field
+// of a VLoggoConfig. Entries are sent in RFC 5424 format with the client as APP-NAME.
+// The sink receives entries at or above level, every entry if level is not provided.
+// It replaces a syslog sink previously added with the same network and address.
+func WithSyslog(cfg types.VLoggoConfig, syslog types.Syslog, level ...types.LogLevel) Option {
+	return func(cfg *types.VLoggoConfig) {
+		cfg.Outputs = addOutput(cfg.Outputs, types.Output{
+			Key: "syslog " + syslog.Network + " " + syslog.Address,
+			New: func(cfg types.VLoggoConfig) types.Sink {
+				return services.NewSyslogSink(syslog, cfg.Client)
+			},
+			Level: minLevel(level),
+		})
+	}
+}
+
+// WithJournald returns an Option function that appends a journald sink to the Outputs field
+// of a VLoggoConfig. If socket is empty, the default journald socket is used.
+// Structured fields are sent as VLOGGO_F_<KEY> journal fields.
+// The sink receives entries at or above level, every entry if level is not provided.
+// It replaces a journald sink previously added with the same socket.
+func WithJournald(cfg types.VLoggoConfig, socket string, level ...types.LogLevel) Option {
+	return func(cfg *types.VLoggoConfig) {
+		if socket == "" {
+			socket = services.JournaldSocket
+		}
+
+		cfg.Outputs = addOutput(cfg.Outputs, types.Output{
+			Key: "journald " + socket,
+			New: func(cfg types.VLoggoConfig) types.Sink {
+				return services.NewJournaldSink(socket, cfg.Client)
+			},
+			Level: minLevel(level),
+		})
+	}
+}
+
+// WithWebhook returns an Option function that appends an HTTP webhook sink to the Outputs field
+// of a VLoggoConfig. Entries are posted in batches as a JSON array of JSON lines.
+// Failed batches are spooled under the Directory.Json of the instance, unless webhook.Spool is set.
+// The sink receives entries at or above level, every entry if level is not provided.
+// It replaces a webhook sink previously added with the same URL.
+func WithWebhook(cfg types.VLoggoConfig, webhook types.Webhook, level ...types.LogLevel) Option {
+	return func(cfg *types.VLoggoConfig) {
+		cfg.Outputs = addOutput(cfg.Outputs, types.Output{
+			Key: "webhook " + webhook.URL,
+			New: func(cfg types.VLoggoConfig) types.Sink {
+				return services.NewWebhookSink(webhook, cfg.Client, cfg.Directory.Json)
+			},
+			Level: minLevel(level),
+		})
+	}
+}
+
+// WithLoki returns an Option function that appends a Grafana Loki sink to the Outputs field
+// of a VLoggoConfig. Entries are pushed in batches, labeled by client and level.
+// The sink receives entries at or above level, every entry if level is not provided.
+// It replaces a Loki sink previously added with the same URL.
+func WithLoki(cfg types.VLoggoConfig, loki types.Loki, level ...types.LogLevel) Option {
+	return func(cfg *types.VLoggoConfig) {
+		cfg.Outputs = addOutput(cfg.Outputs, types.Output{
+			Key: "loki " + loki.URL,
+			New: func(cfg types.VLoggoConfig) types.Sink {
+				return services.NewLokiSink(loki, cfg.Client)
+			},
+			Level: minLevel(level),
+		})
+	}
+}
+
+// WithElastic returns an Option function that appends an Elasticsearch/OpenSearch sink to the Outputs field
+// of a VLoggoConfig. Entries are indexed in batches through the bulk API into daily indices.
+// Rejected documents are written under the Directory.Json of the instance, unless elastic.DeadLetter is set.
+// The sink receives entries at or above level, every entry if level is not provided.
+// It replaces an Elasticsearch sink previously added with the same URL and index.
+func WithElastic(cfg types.VLoggoConfig, elastic types.Elastic, level ...types.LogLevel) Option {
+	return func(cfg *types.VLoggoConfig) {
+		cfg.Outputs = addOutput(cfg.Outputs, types.Output{
+			Key: "elastic " + elastic.URL + " " + elastic.Index,
+			New: func(cfg types.VLoggoConfig) types.Sink {
+				return services.NewElasticSink(elastic, cfg.Client, cfg.Directory.Json)
+			},
+			Level: minLevel(level),
+		})
+	}
+}
+
 // WithThrottle returns an Option function that sets the Throttle (seconds) field
 // of a VLoggoConfig.
 func WithThrottle(cfg types.VLoggoConfig, seconds int) Option {
@@ -222,14 +419,65 @@ func WithThrottle(cfg types.VLoggoConfig, seconds int) Option {
 	}
 }
 
+// WithMaxSize returns an Option function that sets the MaxSize (bytes) field
+// of a VLoggoConfig. Zero disables size-based rotation.
+func WithMaxSize(cfg types.VLoggoConfig, bytes int64) Option {
+	return func(cfg *types.VLoggoConfig) {
+		cfg.MaxSize = bytes
+	}
+}
+
+// WithCompress returns an Option function that sets the Compress (enabled) field
+// of a VLoggoConfig. Rotated files are gzipped in the background.
+func WithCompress(cfg types.VLoggoConfig, enabled bool) Option {
+	return func(cfg *types.VLoggoConfig) {
+		cfg.Compress = enabled
+	}
+}
+
 // WithFilecount returns an Option function that sets the Filecount (Txt/Json) field
-// of a VLoggoConfig.
+// of a VLoggoConfig. Filecount is the number of rotation periods kept, so the files
+// a period was split into by MaxSize count once.
 func WithFilecount(cfg types.VLoggoConfig, filecount types.Count) Option {
 	return func(cfg *types.VLoggoConfig) {
 		cfg.Filecount = filecount
 	}
 }
 
+// WithRetention returns an Option function that sets the Retention (max age/total bytes) field
+// of a VLoggoConfig. It is combined with Filecount when old files are deleted. MaxAge is
+// measured from the end of the rotation period a file belongs to.
+func WithRetention(cfg types.VLoggoConfig, retention types.Retention) Option {
+	return func(cfg *types.VLoggoConfig) {
+		cfg.Retention = retention
+	}
+}
+
+// WithRollover returns an Option function that sets the Rollover (interval/location/timer) field
+// of a VLoggoConfig. Interval accepts any duration, rounded to whole seconds with a minimum of one second.
+// With Timer enabled, files roll over at the end of each interval even if nothing is logged.
+func WithRollover(cfg types.VLoggoConfig, rollover types.Rollover) Option {
+	return func(cfg *types.VLoggoConfig) {
+		cfg.Rollover = rollover
+	}
+}
+
+// WithFlush returns an Option function that sets the Flush (size/interval/level) field
+// of a VLoggoConfig.
+func WithFlush(cfg types.VLoggoConfig, flush types.Flush) Option {
+	return func(cfg *types.VLoggoConfig) {
+		cfg.Flush = flush
+	}
+}
+
+// WithAsync returns an Option function that sets the Async (enabled/queue/overflow) field
+// of a VLoggoConfig. It only takes effect when the instance is created.
+func WithAsync(cfg types.VLoggoConfig, async types.Async) Option {
+	return func(cfg *types.VLoggoConfig) {
+		cfg.Async = async
+	}
+}
+
 // WithDirectory returns an Option function that sets the Directory (paths) field
 // of a VLoggoConfig.
 func WithDirectory(cfg types.VLoggoConfig, paths types.Paths) Option {
@@ -238,6 +486,16 @@ func WithDirectory(cfg types.VLoggoConfig, paths types.Paths) Option {
 	}
 }
 
+// WithTemplates returns an Option function that sets the Templates (txt/json/current) field
+// of a VLoggoConfig. Templates accept {client}, {date}, {pid} and {index} placeholders and
+// Current keeps a symlink pointing at the active file in each log directory. The link is named
+// current plus the extension of the template, current.txt and current.jsonl with the defaults.
+func WithTemplates(cfg types.VLoggoConfig, templates types.Templates) Option {
+	return func(cfg *types.VLoggoConfig) {
+		cfg.Templates = templates
+	}
+}
+
 // WithSMTP returns an Option function that sets the SMTP field
 // of a VLoggoConfig.
 func WithSMTP(cfg types.VLoggoConfig, smtp types.VLoggoSMTP) Option {
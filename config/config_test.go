@@ -0,0 +1,29 @@
+package config
+
+import (
+	"testing"
+
+	types "github.com/vinialx/vloggo-go/types"
+)
+
+func TestDefaultLevel(t *testing.T) {
+	tests := []struct {
+		env  string
+		want types.LogLevel
+	}{
+		{"", types.Debug},
+		{"INFO", types.Info},
+		{" warn ", types.Warn},
+		{"Error", types.Error},
+		{"FATAL", types.Fatal},
+		{"verbose", types.Debug},
+	}
+
+	for _, tt := range tests {
+		t.Setenv("LOG_LEVEL", tt.env)
+
+		if got := DefaultLevel("test"); got != tt.want {
+			t.Errorf("LOG_LEVEL=%q gives %s, want %s", tt.env, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	types "github.com/vinialx/vloggo-go/types"
+)
+
+// defaultQueueSize is the queue size used when cfg.Async.Queue is not set
+const defaultQueueSize = 1024
+
+// AsyncService manages the asynchronous logging pipeline
+// Log calls push entries onto a bounded queue and a background goroutine hands them to the writer
+// When the queue is full, cfg.Async.Overflow decides whether to block or drop entries
+type AsyncService struct {
+	client   string
+	format   *FormatService
+	queue    chan types.LogEntry
+	handle   func(types.LogEntry)
+	overflow types.Overflow
+	dropped  atomic.Uint64
+
+	pending int
+	idle    chan struct{}
+	closed  bool
+	stopped chan struct{}
+
+	mu     sync.RWMutex
+	pendMu sync.Mutex
+	dropMu sync.Mutex
+}
+
+// NewAsyncService creates a new AsyncService instance and starts its background goroutine
+// handle is called for every entry, in order, from that goroutine
+func NewAsyncService(cfg types.VLoggoConfig, handle func(types.LogEntry)) *AsyncService {
+	size := cfg.Async.Queue
+	if size <= 0 {
+		size = defaultQueueSize
+	}
+
+	overflow := cfg.Async.Overflow
+	if overflow == "" {
+		overflow = types.Block
+	}
+
+	idle := make(chan struct{})
+	close(idle)
+
+	as := &AsyncService{
+		client:   cfg.Client,
+		format:   NewFormatService(cfg.Client),
+		queue:    make(chan types.LogEntry, size),
+		handle:   handle,
+		overflow: overflow,
+		idle:     idle,
+		stopped:  make(chan struct{}),
+	}
+
+	go as.run()
+
+	return as
+}
+
+// run hands queued entries to the writer until the queue is closed
+func (as *AsyncService) run() {
+	defer close(as.stopped)
+
+	for entry := range as.queue {
+		as.process(entry)
+		as.done()
+	}
+}
+
+// process hands a single entry to the writer, recovering from panics so the pipeline keeps running
+func (as *AsyncService) process(entry types.LogEntry) {
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Printf("[VLoggo] > [%s] [%s] [ERROR] : async writer panicked > %v\n",
+				as.client,
+				as.format.Date(),
+				r,
+			)
+		}
+	}()
+
+	as.handle(entry)
+}
+
+// add marks one more entry as pending
+func (as *AsyncService) add() {
+	as.pendMu.Lock()
+	defer as.pendMu.Unlock()
+
+	if as.pending == 0 {
+		as.idle = make(chan struct{})
+	}
+	as.pending++
+}
+
+// done marks one pending entry as written or dropped
+func (as *AsyncService) done() {
+	as.pendMu.Lock()
+	defer as.pendMu.Unlock()
+
+	as.pending--
+	if as.pending == 0 {
+		close(as.idle)
+	}
+}
+
+// kept reports whether an entry must never be dropped by the overflow policy
+// ERROR and FATAL entries are kept, FATAL ones are followed by os.Exit
+func kept(entry types.LogEntry) bool {
+	return entry.Level.Severity() >= types.Error.Severity()
+}
+
+// Push adds an entry to the queue following the overflow policy
+// ERROR and FATAL entries bypass the policy and wait for room in the queue
+// DropOldest drops the oldest queued entry that is not an ERROR or FATAL one,
+// and waits for room when every queued entry must be kept
+// Returns false if the entry was dropped or the service is closed
+func (as *AsyncService) Push(entry types.LogEntry) bool {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+
+	if as.closed {
+		return false
+	}
+
+	as.add()
+
+	switch as.overflow {
+	case types.DropNewest:
+		if kept(entry) {
+			as.queue <- entry
+			return true
+		}
+
+		select {
+		case as.queue <- entry:
+			return true
+		default:
+			as.dropped.Add(1)
+			as.done()
+			return false
+		}
+
+	case types.DropOldest:
+		as.dropMu.Lock()
+		defer as.dropMu.Unlock()
+
+		for !kept(entry) {
+			select {
+			case as.queue <- entry:
+				return true
+			default:
+			}
+
+			if !as.evict() {
+				break
+			}
+		}
+
+		as.queue <- entry
+		return true
+
+	default:
+		as.queue <- entry
+		return true
+	}
+}
+
+// evict drops the oldest queued entry that is not kept
+// Kept entries taken off the queue are pushed back in the same order, so the writer still sees every entry in order
+// Returns false if every queued entry must be kept
+// Must be called with as.dropMu held, so no other Push can add entries in between
+func (as *AsyncService) evict() bool {
+	var queued []types.LogEntry
+
+	select {
+	case oldest := <-as.queue:
+		if !kept(oldest) {
+			as.dropped.Add(1)
+			as.done()
+			return true
+		}
+		queued = append(queued, oldest)
+	default:
+		return true
+	}
+
+	for len(as.queue) > 0 {
+		select {
+		case entry := <-as.queue:
+			queued = append(queued, entry)
+		default:
+		}
+	}
+
+	evicted := false
+	for _, entry := range queued {
+		if !evicted && !kept(entry) {
+			evicted = true
+			as.dropped.Add(1)
+			as.done()
+			continue
+		}
+		as.queue <- entry
+	}
+
+	return evicted
+}
+
+// Dropped returns the number of entries dropped because the queue was full
+func (as *AsyncService) Dropped() uint64 {
+	return as.dropped.Load()
+}
+
+// Flush waits until every entry pushed so far has been handed to the writer
+// Returns ctx.Err() if the context ends first
+func (as *AsyncService) Flush(ctx context.Context) error {
+	as.pendMu.Lock()
+	idle := as.idle
+	as.pendMu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops accepting entries, drains the queue and stops the background goroutine
+// Returns ctx.Err() if the context ends before the queue is drained
+// Calling Close more than once has no effect
+func (as *AsyncService) Close(ctx context.Context) error {
+	as.mu.Lock()
+	if !as.closed {
+		as.closed = true
+		close(as.queue)
+	}
+	as.mu.Unlock()
+
+	select {
+	case <-as.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	types "github.com/vinialx/vloggo-go/types"
+)
+
+// blockedAsync creates an AsyncService with a queue of size entries whose writer blocks on its first entry
+// until release is closed. Returns the service and a function listing the codes handled so far
+func blockedAsync(t *testing.T, overflow types.Overflow, size int) (*AsyncService, chan struct{}, func() []string) {
+	t.Helper()
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+
+	var mu sync.Mutex
+	var handled []string
+	first := true
+
+	as := NewAsyncService(types.VLoggoConfig{
+		Client: "test",
+		Async:  types.Async{Enabled: true, Queue: size, Overflow: overflow},
+	}, func(entry types.LogEntry) {
+		mu.Lock()
+		wait := first
+		first = false
+		mu.Unlock()
+
+		if wait {
+			close(started)
+			<-release
+		}
+
+		mu.Lock()
+		handled = append(handled, entry.Code)
+		mu.Unlock()
+	})
+	t.Cleanup(func() { as.Close(context.Background()) })
+
+	as.Push(types.LogEntry{Level: types.Info, Code: "BUSY"})
+	<-started
+
+	return as, release, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), handled...)
+	}
+}
+
+func TestPushDropNewestKeepsFatal(t *testing.T) {
+	as, release, handled := blockedAsync(t, types.DropNewest, 1)
+
+	as.Push(types.LogEntry{Level: types.Info, Code: "QUEUED"})
+	if as.Push(types.LogEntry{Level: types.Info, Code: "DROPPED"}) {
+		t.Fatal("INFO entry pushed onto a full DropNewest queue")
+	}
+
+	pushed := make(chan bool)
+	go func() {
+		pushed <- as.Push(types.LogEntry{Level: types.Fatal, Code: "FATAL"})
+	}()
+
+	select {
+	case <-pushed:
+		t.Fatal("FATAL entry did not wait for room in the queue")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	if !<-pushed {
+		t.Fatal("FATAL entry dropped")
+	}
+
+	if err := as.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	got := handled()
+	if len(got) != 3 || got[2] != "FATAL" {
+		t.Fatalf("handled %v, want [BUSY QUEUED FATAL]", got)
+	}
+	if as.Dropped() != 1 {
+		t.Errorf("dropped %d entries, want 1", as.Dropped())
+	}
+}
+
+func TestPushDropOldestKeepsError(t *testing.T) {
+	as, release, handled := blockedAsync(t, types.DropOldest, 2)
+
+	as.Push(types.LogEntry{Level: types.Error, Code: "ERROR"})
+	as.Push(types.LogEntry{Level: types.Info, Code: "OLDEST"})
+	if !as.Push(types.LogEntry{Level: types.Info, Code: "LATEST"}) {
+		t.Fatal("INFO entry not pushed onto a DropOldest queue")
+	}
+
+	if got := handled(); len(got) != 0 {
+		t.Fatalf("handled %v while the writer was blocked, Push must not write entries itself", got)
+	}
+
+	close(release)
+	if err := as.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	got := handled()
+	if strings.Join(got, " ") != "BUSY ERROR LATEST" {
+		t.Fatalf("handled %v, want [BUSY ERROR LATEST]", got)
+	}
+	if as.Dropped() != 1 {
+		t.Errorf("dropped %d entries, want 1", as.Dropped())
+	}
+}
+
+func TestPushDropOldestWaitsForKeptEntries(t *testing.T) {
+	as, release, handled := blockedAsync(t, types.DropOldest, 1)
+
+	as.Push(types.LogEntry{Level: types.Error, Code: "ERROR"})
+
+	pushed := make(chan bool)
+	go func() {
+		pushed <- as.Push(types.LogEntry{Level: types.Info, Code: "LATEST"})
+	}()
+
+	select {
+	case <-pushed:
+		t.Fatal("INFO entry did not wait while the queue only held an ERROR entry")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if got := handled(); len(got) != 0 {
+		t.Fatalf("handled %v while the writer was blocked, Push must not write entries itself", got)
+	}
+
+	close(release)
+	if !<-pushed {
+		t.Fatal("INFO entry dropped")
+	}
+
+	if err := as.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	got := handled()
+	if strings.Join(got, " ") != "BUSY ERROR LATEST" {
+		t.Fatalf("handled %v, want [BUSY ERROR LATEST]", got)
+	}
+	if as.Dropped() != 0 {
+		t.Errorf("dropped %d entries, want 0", as.Dropped())
+	}
+}
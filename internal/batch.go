@@ -0,0 +1,258 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	types "github.com/vinialx/vloggo-go/types"
+)
+
+// Defaults used by the batching sinks when their config leaves a value unset
+const (
+	defaultBatchSize     = 100
+	defaultBatchInterval = 5 * time.Second
+	defaultRetries       = 3
+	defaultBackoff       = time.Second
+	defaultHTTPTimeout   = 10 * time.Second
+)
+
+// maxBackoff caps the delay between two retries
+const maxBackoff = time.Minute
+
+// maxPendingBatches caps the entries waiting to be sent, in batches, while sends are failing or slow
+const maxPendingBatches = 100
+
+// batcher groups entries into batches handed to send from a background goroutine
+// A batch is sent when it reaches size entries or when interval elapses, whichever comes first
+// Sends never run concurrently, and a failed batch is left to send to handle, unless keep is set:
+// then batches failing with a temporary error are put back in front of the pending entries
+// send receives a channel closed when the batcher is closing, to cut retries short
+// At most maxPendingBatches batches wait to be sent, newer entries are dropped and counted
+type batcher struct {
+	client   string
+	format   *FormatService
+	size     int
+	limit    int
+	keep     bool
+	interval time.Duration
+	send     func(stop <-chan struct{}, batch []types.LogEntry) error
+	dropped  atomic.Uint64
+
+	pending []types.LogEntry
+	closed  bool
+	kick    chan struct{}
+	done    chan struct{}
+	stopped chan struct{}
+
+	mu     sync.Mutex
+	sendMu sync.Mutex
+}
+
+// newBatcher creates a new batcher and starts its background goroutine
+// A size or interval of 0 uses defaultBatchSize or defaultBatchInterval
+func newBatcher(client string, size int, interval time.Duration, keep bool, send func(stop <-chan struct{}, batch []types.LogEntry) error) *batcher {
+	if size <= 0 {
+		size = defaultBatchSize
+	}
+	if interval <= 0 {
+		interval = defaultBatchInterval
+	}
+
+	b := &batcher{
+		client:   client,
+		format:   NewFormatService(client),
+		size:     size,
+		limit:    size * maxPendingBatches,
+		keep:     keep,
+		interval: interval,
+		send:     send,
+		kick:     make(chan struct{}, 1),
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+
+	go b.run()
+
+	return b
+}
+
+// run sends the pending entries every interval, or sooner when a batch is full, until the batcher is closed
+func (b *batcher) run() {
+	defer close(b.stopped)
+
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.done:
+			return
+		case <-ticker.C:
+		case <-b.kick:
+		}
+
+		if err := b.flush(); err != nil {
+			fmt.Printf("[VLoggo] > [%s] [%s] [ERROR] : failed to send batch > %v\n",
+				b.client,
+				b.format.Date(),
+				err,
+			)
+		}
+	}
+}
+
+// add queues an entry for the next batch
+// The entry is dropped when limit entries are already waiting
+func (b *batcher) add(entry types.LogEntry) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return errors.New("sink is closed")
+	}
+
+	if len(b.pending) >= b.limit {
+		b.dropped.Add(1)
+		return nil
+	}
+
+	b.pending = append(b.pending, entry)
+
+	if len(b.pending) >= b.size {
+		select {
+		case b.kick <- struct{}{}:
+		default:
+		}
+	}
+
+	return nil
+}
+
+// flush sends every pending entry, in batches of at most size entries
+// With keep set, the first batch failing with a temporary error is requeued with the entries after it
+func (b *batcher) flush() error {
+	b.sendMu.Lock()
+	defer b.sendMu.Unlock()
+
+	b.mu.Lock()
+	pending := b.pending
+	b.pending = nil
+	b.mu.Unlock()
+
+	var errs []error
+	for len(pending) > 0 {
+		n := min(len(pending), b.size)
+		err := b.send(b.done, pending[:n])
+
+		if err != nil && b.keep && !permanent(err) {
+			kept := b.requeue(pending)
+			errs = append(errs, fmt.Errorf("%w, %d of %d entries kept for the next send", err, kept, len(pending)))
+			break
+		}
+
+		if err != nil {
+			errs = append(errs, err)
+		}
+		pending = pending[n:]
+	}
+
+	return errors.Join(errs...)
+}
+
+// requeue puts entries back in front of the pending ones, keeping at most limit entries
+// The newest entries beyond limit are dropped, and every entry once the batcher is closed
+// Returns the number of entries requeued
+func (b *batcher) requeue(entries []types.LogEntry) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		b.dropped.Add(uint64(len(entries)))
+		return 0
+	}
+
+	pending := append(entries[:len(entries):len(entries)], b.pending...)
+	if len(pending) > b.limit {
+		b.dropped.Add(uint64(len(pending) - b.limit))
+		pending = pending[:b.limit]
+	}
+	b.pending = pending
+
+	return min(len(entries), b.limit)
+}
+
+// close stops the background goroutine and sends the remaining entries
+// Retries still waiting are cut short, see retry
+// Calling close more than once has no effect
+func (b *batcher) close() error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	close(b.done)
+	b.mu.Unlock()
+
+	<-b.stopped
+
+	return b.flush()
+}
+
+// attempts converts a configured number of retries into a number of attempts
+// 0 uses defaultRetries and a negative value disables retries
+func attempts(retries int) int {
+	switch {
+	case retries == 0:
+		return defaultRetries + 1
+	case retries < 0:
+		return 1
+	default:
+		return retries + 1
+	}
+}
+
+// retryDelay returns the delay before an attempt, doubling from backoff after each one up to maxBackoff
+// A statusError with a Retry-After delay overrides the backoff, capped at maxBackoff as well
+// so a server asking for hours does not hold the only sending goroutine
+func retryDelay(attempt int, backoff time.Duration, err error) time.Duration {
+	delay := maxBackoff
+	if shift := attempt - 1; shift < 32 && backoff<<shift > 0 && backoff<<shift < maxBackoff {
+		delay = backoff << shift
+	}
+
+	var status *statusError
+	if errors.As(err, &status) && status.After > 0 {
+		delay = min(status.After, maxBackoff)
+	}
+
+	return delay
+}
+
+// retry calls fn up to attempts times, waiting retryDelay between calls
+// Stops early when fn succeeds, returns a permanent error, or stop is closed
+func retry(stop <-chan struct{}, attempts int, backoff time.Duration, fn func() error) error {
+	var err error
+
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			timer := time.NewTimer(retryDelay(attempt, backoff, err))
+			select {
+			case <-stop:
+				timer.Stop()
+				return err
+			case <-timer.C:
+			}
+		}
+
+		err = fn()
+		if err == nil || permanent(err) {
+			return err
+		}
+	}
+
+	return err
+}
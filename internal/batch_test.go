@@ -0,0 +1,51 @@
+package services
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	types "github.com/vinialx/vloggo-go/types"
+)
+
+// batchServer is the HTTP server behind the webhook, Loki and Elasticsearch tests
+// handle answers each request, numbered from 1, with mu held so it can record
+// what it received in fields guarded by the same lock
+type batchServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests int
+}
+
+func newBatchServer(t *testing.T, handle func(request int, w http.ResponseWriter, r *http.Request)) *batchServer {
+	t.Helper()
+
+	bs := &batchServer{}
+	bs.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bs.mu.Lock()
+		defer bs.mu.Unlock()
+
+		bs.requests++
+		handle(bs.requests, w, r)
+	}))
+	t.Cleanup(bs.Close)
+
+	return bs
+}
+
+// newTestSink applies edit to cfg, creates the sink with create and closes it when the test ends
+// The callers set a long Interval, so the sink only sends on Flush, and a short Backoff
+func newTestSink[C any, S types.Sink](t *testing.T, cfg C, edit func(cfg *C), create func(cfg C) S) S {
+	t.Helper()
+
+	if edit != nil {
+		edit(&cfg)
+	}
+
+	s := create(cfg)
+	t.Cleanup(func() { s.Close() })
+
+	return s
+}
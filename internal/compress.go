@@ -0,0 +1,211 @@
+package services
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// gzipExt is appended to the name of compressed log files
+const gzipExt = ".gz"
+
+// gzipTmpExt is appended to archives being written, they are renamed to .gz once complete
+const gzipTmpExt = ".gz.tmp"
+
+// compressFile gzips path into path.gz and removes path
+// The archive is written to path.gz.tmp, synced and renamed, so a .gz file is always complete
+// Gives up and keeps path if it grew while being compressed, since another writer still appends to it
+// The archive keeps the modification time of the original file
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("error opening %s > %w", path, err)
+	}
+	defer src.Close()
+
+	info, err := src.Stat()
+	if err != nil {
+		return fmt.Errorf("error reading %s > %w", path, err)
+	}
+
+	tmpPath := path + gzipTmpExt
+	dst, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("error creating %s > %w", tmpPath, err)
+	}
+
+	zw := gzip.NewWriter(dst)
+	zw.Name = filepath.Base(path)
+
+	written, err := io.Copy(zw, src)
+	if err != nil {
+		dst.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("error compressing %s > %w", path, err)
+	}
+
+	if err := zw.Close(); err != nil {
+		dst.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("error finishing %s > %w", tmpPath, err)
+	}
+
+	if err := dst.Sync(); err != nil {
+		dst.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("error syncing %s > %w", tmpPath, err)
+	}
+
+	if err := dst.Close(); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("error closing %s > %w", tmpPath, err)
+	}
+
+	if current, err := os.Stat(path); err != nil || current.Size() != written {
+		os.Remove(tmpPath)
+		return fmt.Errorf("error compressing %s > file changed during compression", path)
+	}
+
+	mtime := info.ModTime()
+	if err := os.Chtimes(tmpPath, mtime, mtime); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("error setting times on %s > %w", tmpPath, err)
+	}
+
+	if err := os.Rename(tmpPath, path+gzipExt); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("error renaming %s > %w", tmpPath, err)
+	}
+
+	src.Close()
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("error removing %s > %w", path, err)
+	}
+
+	return nil
+}
+
+// compress gzips a rotated log file in the background
+// The active txt and json files, and files of the current period this service has not released, are never compressed
+// Must be called with fs.mu held
+func (fs *FileService) compress(path string) {
+	if !fs.cfg.Compress || fs.isActive(path) {
+		return
+	}
+
+	if !fs.releasable(path, fs.fileDate(path)) {
+		return
+	}
+
+	if fs.compressing[path] {
+		return
+	}
+	fs.compressing[path] = true
+
+	fs.wg.Add(1)
+	go func() {
+		defer fs.wg.Done()
+
+		err := compressFile(path)
+
+		fs.mu.Lock()
+		delete(fs.compressing, path)
+		fs.mu.Unlock()
+
+		if err != nil {
+			fmt.Printf("[VLoggo] > [%s] [%s] [ERROR] : error compressing rotated file > %v\n",
+				fs.cfg.Client,
+				fs.format.Date(),
+				err,
+			)
+		}
+	}()
+}
+
+// compressRotated compresses every rotated file left uncompressed in the log directories
+// Runs at startup and at every new period, so files of a period still being written elsewhere are picked up later
+// Also removes partial archives left behind by a crash during compression
+// Must be called with fs.mu held
+func (fs *FileService) compressRotated() {
+	if !fs.cfg.Compress {
+		return
+	}
+
+	fs.compressDir(fs.cfg.Directory.Txt, filepath.Ext(fs.format.Filename()))
+	if fs.cfg.Json {
+		fs.compressDir(fs.cfg.Directory.Json, filepath.Ext(fs.format.JSONFilename()))
+	}
+}
+
+// compressDir compresses the rotated files with the given extension in dir
+// Must be called with fs.mu held
+func (fs *FileService) compressDir(dir, ext string) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+
+	for _, file := range files {
+		name := file.Name()
+		path := filepath.Join(dir, name)
+
+		if strings.HasSuffix(name, gzipTmpExt) {
+			if !fs.compressing[strings.TrimSuffix(path, gzipTmpExt)] {
+				os.Remove(path)
+			}
+			continue
+		}
+
+		if file.Type()&os.ModeSymlink != 0 {
+			continue
+		}
+
+		if _, _, ok := fs.format.ParseFilename(name); ok && filepath.Ext(name) == ext {
+			fs.compress(path)
+		}
+	}
+}
+
+// fileDate returns the period start in the name of path, or its modification time when the name has none
+// Returns the zero time if path cannot be read
+func (fs *FileService) fileDate(path string) time.Time {
+	if date, _, ok := fs.format.ParseFilename(filepath.Base(path)); ok {
+		return date
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return info.ModTime()
+}
+
+// releasable reports whether path, dated date, may be compressed or deleted
+// Files from earlier periods always are. Files of the current period may still be written by
+// another instance or process sharing the directory, so they are only released once this service
+// has rotated away from them and their size shows nobody else appended to them since
+// Must be called with fs.mu held
+func (fs *FileService) releasable(path string, date time.Time) bool {
+	if fs.format.Period(date) != fs.period {
+		return true
+	}
+
+	size, ok := fs.released[path]
+	if !ok {
+		return false
+	}
+
+	info, err := os.Stat(path)
+	return err == nil && info.Size() == size
+}
+
+// isActive reports whether path is one of the files currently being written
+// Must be called with fs.mu held
+func (fs *FileService) isActive(path string) bool {
+	return (fs.txt != nil && fs.txt.path == path) || (fs.json != nil && fs.json.path == path)
+}
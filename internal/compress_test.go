@@ -0,0 +1,117 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	types "github.com/vinialx/vloggo-go/types"
+)
+
+func TestCompressRotatedOwnFile(t *testing.T) {
+	fs := newTestFileService(t, func(cfg *types.VLoggoConfig) {
+		cfg.MaxSize = 300
+		cfg.Compress = true
+	})
+	dir := fs.cfg.Directory.Txt
+
+	for i := 0; i < 10; i++ {
+		if err := fs.WriteTxt(types.Info, "a line long enough to fill the file quickly\n"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := fs.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, fs.format.Filename()+gzipExt)); err != nil {
+		t.Fatalf("rotated file not compressed > %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, fs.format.Filename())); !os.IsNotExist(err) {
+		t.Fatalf("rotated file not removed after compression > %v", err)
+	}
+}
+
+func TestCompressSkipsFileSharedWithAnotherWriter(t *testing.T) {
+	a := newTestFileService(t, func(cfg *types.VLoggoConfig) {
+		cfg.MaxSize = 300
+		cfg.Compress = true
+	})
+	dir := a.cfg.Directory.Txt
+
+	b := NewFileService(types.VLoggoConfig{
+		Client:    "test",
+		Directory: types.Paths{Txt: dir, Json: filepath.Join(dir, "json")},
+	})
+	defer b.Close()
+
+	if err := b.WriteTxt(types.Info, "written by b before\n"); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 10; i++ {
+		if err := a.WriteTxt(types.Info, "a line long enough to fill the file quickly\n"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.WriteTxt(types.Info, "written by b after\n"); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, a.format.Filename()))
+	if err != nil {
+		t.Fatalf("file shared with another writer was removed > %v", err)
+	}
+	for _, line := range []string{"written by b before", "written by b after"} {
+		if !strings.Contains(string(content), line) {
+			t.Errorf("%q lost from the shared file", line)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, a.format.Filename()+gzipExt)); !os.IsNotExist(err) {
+		t.Errorf("file shared with another writer was compressed > %v", err)
+	}
+}
+
+func TestCompressRotatedSkipsCurrentPeriodOfOtherProcesses(t *testing.T) {
+	dir := t.TempDir()
+	format := NewFormatService("test")
+	format.TxtTemplate = "log-{date}-{pid}.txt"
+
+	current := "log-" + format.Period(time.Now()) + "-1.txt"
+	old := "log-" + format.Period(time.Now().AddDate(0, 0, -2)) + "-1.txt"
+	for _, name := range []string{current, old} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("written by pid 1\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	fs := NewFileService(types.VLoggoConfig{
+		Client:    "test",
+		Compress:  true,
+		Directory: types.Paths{Txt: dir, Json: filepath.Join(dir, "json")},
+		Templates: types.Templates{Txt: "log-{date}-{pid}.txt"},
+		Filecount: types.Count{Txt: 1},
+	})
+	if err := fs.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, current)); err != nil {
+		t.Errorf("live file of another process was touched > %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, old+gzipExt)); err != nil {
+		t.Errorf("file of an earlier period not compressed > %v", err)
+	}
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"io"
+	"os"
+	"sync"
+
+	types "github.com/vinialx/vloggo-go/types"
+)
+
+// ConsoleService manages log output to the terminal
+// INFO and DEBUG entries go to stdout, WARN, ERROR and FATAL entries go to stderr
+// Colors are enabled per stream only when it is a terminal and NO_COLOR is not set
+type ConsoleService struct {
+	format *FormatService
+
+	stdout      io.Writer
+	stderr      io.Writer
+	colorStdout bool
+	colorStderr bool
+	mu          sync.Mutex
+}
+
+// NewConsoleService creates a new ConsoleService instance
+// Writes to os.Stdout and os.Stderr until SetOutput is called
+func NewConsoleService(client string) *ConsoleService {
+	return &ConsoleService{
+		format:      NewFormatService(client),
+		stdout:      os.Stdout,
+		stderr:      os.Stderr,
+		colorStdout: colorEnabled(os.Stdout),
+		colorStderr: colorEnabled(os.Stderr),
+	}
+}
+
+// colorEnabled reports whether ANSI colors should be written to w
+// Returns false when NO_COLOR is set or when w is not a character device
+func colorEnabled(w io.Writer) bool {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// SetOutput replaces the writers used for stdout and stderr
+// A nil writer keeps the current one
+func (cs *ConsoleService) SetOutput(stdout, stderr io.Writer) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	if stdout != nil {
+		cs.stdout = stdout
+		cs.colorStdout = colorEnabled(stdout)
+	}
+	if stderr != nil {
+		cs.stderr = stderr
+		cs.colorStderr = colorEnabled(stderr)
+	}
+}
+
+// Write formats the entry and writes it to the stream matching its level
+func (cs *ConsoleService) Write(entry types.LogEntry) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	w, color := cs.stdout, cs.colorStdout
+	switch entry.Level {
+	case types.Warn, types.Error, types.Fatal:
+		w, color = cs.stderr, cs.colorStderr
+	}
+
+	line := cs.format.Line(entry)
+	if color {
+		line = cs.format.ColorLine(entry)
+	}
+
+	_, err := io.WriteString(w, line)
+	return err
+}
+
+// Flush has nothing to do since console lines are written unbuffered
+func (cs *ConsoleService) Flush() error {
+	return nil
+}
+
+// Close leaves stdout and stderr open, they are not owned by the service
+func (cs *ConsoleService) Close() error {
+	return nil
+}
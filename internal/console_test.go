@@ -0,0 +1,54 @@
+package services
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	types "github.com/vinialx/vloggo-go/types"
+)
+
+func TestConsoleStreams(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	cs := NewConsoleService("test")
+	cs.SetOutput(&stdout, &stderr)
+
+	levels := []types.LogLevel{types.Debug, types.Info, types.Warn, types.Error, types.Fatal}
+	for _, level := range levels {
+		if err := cs.Write(types.LogEntry{Level: level, Code: "CODE", Message: string(level) + " message"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, level := range levels {
+		line := string(level) + " message"
+		wantStderr := level == types.Warn || level == types.Error || level == types.Fatal
+
+		if strings.Contains(stderr.String(), line) != wantStderr {
+			t.Errorf("%s on stderr = %v, want %v", level, !wantStderr, wantStderr)
+		}
+		if strings.Contains(stdout.String(), line) == wantStderr {
+			t.Errorf("%s on stdout = %v, want %v", level, wantStderr, !wantStderr)
+		}
+	}
+
+	for name, out := range map[string]string{"stdout": stdout.String(), "stderr": stderr.String()} {
+		if strings.Contains(out, "\x1b[") {
+			t.Errorf("ANSI codes written to captured %s: %q", name, out)
+		}
+	}
+}
+
+func TestConsoleSetOutputKeepsNil(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	cs := NewConsoleService("test")
+	cs.SetOutput(&stdout, &stderr)
+	cs.SetOutput(nil, nil)
+
+	if err := cs.Write(types.LogEntry{Level: types.Info, Message: "kept"}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stdout.String(), "kept") {
+		t.Errorf("nil writer replaced stdout, got %q", stdout.String())
+	}
+}
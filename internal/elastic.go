@@ -0,0 +1,311 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	types "github.com/vinialx/vloggo-go/types"
+)
+
+// ElasticBulkPath is the path of the bulk API
+const ElasticBulkPath = "/_bulk"
+
+// DefaultIndex is the index prefix used when cfg.Index is empty
+const DefaultIndex = "vloggo"
+
+// DeadLetterFile is the name of the dead letter file created in an "elastic" folder under the json log folder
+// The folder keeps it out of the json log files handled by retention
+const DeadLetterFile = "elastic-dead-letter.jsonl"
+
+// invalidIndex matches the characters not allowed in an index name
+var invalidIndex = regexp.MustCompile(`[^a-z0-9_.+-]`)
+
+// ElasticSink indexes batches of entries through the Elasticsearch/OpenSearch bulk API
+// Documents are the JSONLine of the entry, indexed into daily indices named after the entry date (UTC)
+// Items failing with 429 or a server error are retried alone with exponential backoff,
+// items rejected by the server or still failing after every attempt are appended to the dead letter file,
+// as are documents that are not valid JSON, which are never sent
+type ElasticSink struct {
+	cfg        types.Elastic
+	url        string
+	prefix     string
+	deadLetter string
+	attempts   int
+	http       *http.Client
+	format     *FormatService
+	batcher    *batcher
+}
+
+// bulkDoc is one document of a bulk request
+type bulkDoc struct {
+	index  string
+	source string
+}
+
+// bulkResult is the result of one item of a bulk response
+type bulkResult struct {
+	Status int             `json:"status"`
+	Error  json.RawMessage `json:"error,omitempty"`
+}
+
+// bulkResponse is the body of a bulk response, each item maps the action to its result
+type bulkResponse struct {
+	Errors bool                    `json:"errors"`
+	Items  []map[string]bulkResult `json:"items"`
+}
+
+// deadLetter is one line of the dead letter file
+type deadLetter struct {
+	Timestamp string          `json:"timestamp"`
+	Index     string          `json:"index"`
+	Status    int             `json:"status,omitempty"`
+	Error     json.RawMessage `json:"error"`
+	Document  json.RawMessage `json:"document"`
+}
+
+// NewElasticSink creates a new ElasticSink for client and starts its batching goroutine
+// Rejected documents go to cfg.DeadLetter, or to DeadLetterFile in an "elastic" folder in dir when it is empty
+func NewElasticSink(cfg types.Elastic, client, dir string) *ElasticSink {
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = defaultBackoff
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultHTTPTimeout
+	}
+
+	url := strings.TrimRight(cfg.URL, "/")
+	if !strings.HasSuffix(url, ElasticBulkPath) {
+		url += ElasticBulkPath
+	}
+
+	index := cfg.Index
+	if index == "" {
+		index = DefaultIndex
+	}
+
+	if client == "" {
+		client = "VLoggo"
+	}
+
+	deadLetter := cfg.DeadLetter
+	if deadLetter == "" {
+		deadLetter = filepath.Join(dir, "elastic", DeadLetterFile)
+	}
+
+	s := &ElasticSink{
+		cfg:        cfg,
+		url:        url,
+		prefix:     indexName(index + "-" + client),
+		deadLetter: deadLetter,
+		attempts:   attempts(cfg.Retries),
+		http:       &http.Client{Timeout: cfg.Timeout},
+		format:     NewFormatService(client),
+	}
+	s.batcher = newBatcher(client, cfg.Batch, cfg.Interval, false, s.send)
+
+	return s
+}
+
+// indexName lowercases name and replaces the characters not allowed in an index name by '_'
+func indexName(name string) string {
+	return invalidIndex.ReplaceAllString(strings.ToLower(name), "_")
+}
+
+// index returns the daily index of an entry: prefix-YYYY.MM.DD
+func (s *ElasticSink) index(entry types.LogEntry) string {
+	t := entry.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+
+	return s.prefix + "-" + t.UTC().Format("2006.01.02")
+}
+
+// encodeBulk formats documents as a bulk request body
+func encodeBulk(docs []bulkDoc) []byte {
+	var b bytes.Buffer
+
+	for _, doc := range docs {
+		action, _ := json.Marshal(map[string]map[string]string{"index": {"_index": doc.index}})
+		b.Write(action)
+		b.WriteByte('\n')
+		b.WriteString(doc.source)
+		b.WriteByte('\n')
+	}
+
+	return b.Bytes()
+}
+
+// bulk sends documents once
+// Returns the documents to retry, those that failed with 429 or a server error,
+// and adds the documents the server refused to rejected
+func (s *ElasticSink) bulk(docs []bulkDoc, rejected *[]deadLetter) ([]bulkDoc, error) {
+	body, err := post(s.http, s.url, "application/x-ndjson", s.cfg.Headers, encodeBulk(docs), s.cfg.Gzip)
+	if err != nil {
+		return docs, err
+	}
+
+	var resp bulkResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return docs, fmt.Errorf("error reading bulk response > %w", err)
+	}
+
+	if !resp.Errors {
+		return nil, nil
+	}
+
+	if len(resp.Items) != len(docs) {
+		return docs, fmt.Errorf("bulk response has %d items for %d documents", len(resp.Items), len(docs))
+	}
+
+	var failed []bulkDoc
+	for i, item := range resp.Items {
+		for _, result := range item {
+			switch {
+			case result.Status >= 200 && result.Status <= 299:
+			case result.Status == http.StatusTooManyRequests || result.Status >= 500:
+				failed = append(failed, docs[i])
+			default:
+				*rejected = append(*rejected, deadLetter{
+					Index:    docs[i].index,
+					Status:   result.Status,
+					Error:    result.Error,
+					Document: json.RawMessage(docs[i].source),
+				})
+			}
+		}
+	}
+
+	if len(failed) > 0 {
+		return failed, fmt.Errorf("%d documents failed temporarily", len(failed))
+	}
+
+	return nil, nil
+}
+
+// send indexes a batch, retrying the failed items and dead-lettering the rejected ones
+// Documents that are not valid JSON are dead-lettered without being sent, they would break the bulk body
+func (s *ElasticSink) send(stop <-chan struct{}, batch []types.LogEntry) error {
+	var docs []bulkDoc
+	var rejected []deadLetter
+
+	for _, entry := range batch {
+		doc := bulkDoc{
+			index:  s.index(entry),
+			source: strings.TrimSuffix(s.format.JSONLine(entry), "\n"),
+		}
+
+		if !json.Valid([]byte(doc.source)) {
+			reason, _ := json.Marshal("document is not valid JSON")
+			rejected = append(rejected, deadLetter{
+				Index:    doc.index,
+				Error:    reason,
+				Document: json.RawMessage(doc.source),
+			})
+			continue
+		}
+
+		docs = append(docs, doc)
+	}
+
+	if len(docs) == 0 {
+		return s.deadLetters(rejected, len(batch))
+	}
+
+	err := retry(stop, s.attempts, s.cfg.Backoff, func() error {
+		var err error
+		docs, err = s.bulk(docs, &rejected)
+		return err
+	})
+
+	if err != nil {
+		reason, _ := json.Marshal(err.Error())
+		for _, doc := range docs {
+			rejected = append(rejected, deadLetter{
+				Index:    doc.index,
+				Error:    reason,
+				Document: json.RawMessage(doc.source),
+			})
+		}
+	}
+
+	return s.deadLetters(rejected, len(batch))
+}
+
+// deadLetters writes the rejected documents of a batch of total documents
+// Returns an error describing them, nil when there are none
+func (s *ElasticSink) deadLetters(rejected []deadLetter, total int) error {
+	if len(rejected) == 0 {
+		return nil
+	}
+
+	if dlErr := s.writeDeadLetters(rejected); dlErr != nil {
+		return fmt.Errorf("error indexing %d of %d documents > %w", len(rejected), total, dlErr)
+	}
+
+	return fmt.Errorf("error indexing %d of %d documents, written to %s", len(rejected), total, s.deadLetter)
+}
+
+// writeDeadLetters appends the rejected documents to the dead letter file
+// A document that is not valid JSON is written as a JSON string
+func (s *ElasticSink) writeDeadLetters(rejected []deadLetter) error {
+	if err := os.MkdirAll(filepath.Dir(s.deadLetter), 0755); err != nil {
+		return fmt.Errorf("error creating dead letter folder > %w", err)
+	}
+
+	file, err := os.OpenFile(s.deadLetter, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("error opening dead letter file > %w", err)
+	}
+	defer file.Close()
+
+	var b bytes.Buffer
+	now := s.format.IsoDate()
+	for _, letter := range rejected {
+		letter.Timestamp = now
+		if !json.Valid(letter.Document) {
+			letter.Document, _ = json.Marshal(string(letter.Document))
+		}
+
+		line, err := json.Marshal(letter)
+		if err != nil {
+			continue
+		}
+		b.Write(line)
+		b.WriteByte('\n')
+	}
+
+	if _, err := file.Write(b.Bytes()); err != nil {
+		return fmt.Errorf("error writing dead letter file > %w", err)
+	}
+
+	return nil
+}
+
+// Write queues the entry for the next bulk request
+func (s *ElasticSink) Write(entry types.LogEntry) error {
+	return s.batcher.add(entry)
+}
+
+// Dropped returns the number of entries dropped because too many were waiting to be indexed
+func (s *ElasticSink) Dropped() uint64 {
+	return s.batcher.dropped.Load()
+}
+
+// Flush indexes the pending entries
+func (s *ElasticSink) Flush() error {
+	return s.batcher.flush()
+}
+
+// Close indexes the pending entries and stops the batching goroutine
+// Pending retries are cut short and their documents dead-lettered
+func (s *ElasticSink) Close() error {
+	return s.batcher.close()
+}
@@ -0,0 +1,231 @@
+package services
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	types "github.com/vinialx/vloggo-go/types"
+)
+
+// elasticServer records the documents of each bulk request
+// status decides the status of each item from the request number, from 1, and the document message
+// A status of 0 fails the whole request with a 503
+type elasticServer struct {
+	*batchServer
+
+	bulks [][]string
+}
+
+func newElasticServer(t *testing.T, status func(request int, message string) int) *elasticServer {
+	t.Helper()
+
+	es := &elasticServer{}
+	es.batchServer = newBatchServer(t, func(request int, w http.ResponseWriter, r *http.Request) {
+		var messages []string
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			var action map[string]map[string]string
+			if err := json.Unmarshal(scanner.Bytes(), &action); err != nil || action["index"]["_index"] == "" {
+				http.Error(w, "bad action "+scanner.Text(), http.StatusBadRequest)
+				return
+			}
+
+			scanner.Scan()
+			var doc struct {
+				Message string `json:"message"`
+			}
+			if err := json.Unmarshal(scanner.Bytes(), &doc); err != nil {
+				http.Error(w, "bad document "+scanner.Text(), http.StatusBadRequest)
+				return
+			}
+			messages = append(messages, doc.Message)
+		}
+
+		es.bulks = append(es.bulks, messages)
+
+		var items []string
+		failed := false
+		for _, message := range messages {
+			code := status(request, message)
+			if code == 0 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+
+			result := fmt.Sprintf(`{"index":{"status":%d}}`, code)
+			if code >= 300 {
+				failed = true
+				result = fmt.Sprintf(`{"index":{"status":%d,"error":{"type":"test_exception","reason":"%s"}}}`, code, message)
+			}
+			items = append(items, result)
+		}
+
+		fmt.Fprintf(w, `{"errors":%v,"items":[%s]}`, failed, strings.Join(items, ","))
+	})
+
+	return es
+}
+
+// readDeadLetters reads the lines of a dead letter file
+func readDeadLetters(t *testing.T, path string) []deadLetter {
+	t.Helper()
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var letters []deadLetter
+	for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+		var letter deadLetter
+		if err := json.Unmarshal([]byte(line), &letter); err != nil {
+			t.Fatalf("dead letter %q is not JSON > %v", line, err)
+		}
+		letters = append(letters, letter)
+	}
+
+	return letters
+}
+
+// newTestElastic creates an ElasticSink indexing to url, see newTestSink
+func newTestElastic(t *testing.T, url string, edit func(cfg *types.Elastic)) *ElasticSink {
+	t.Helper()
+
+	return newTestSink(t, types.Elastic{
+		URL:        url,
+		Interval:   time.Hour,
+		Backoff:    time.Millisecond,
+		DeadLetter: filepath.Join(t.TempDir(), "dead.jsonl"),
+	}, edit, func(cfg types.Elastic) *ElasticSink {
+		return NewElasticSink(cfg, "Test App", "")
+	})
+}
+
+func TestElasticRetriesFailedItems(t *testing.T) {
+	es := newElasticServer(t, func(request int, message string) int {
+		switch {
+		case message == "throttled" && request == 1:
+			return http.StatusTooManyRequests
+		case message == "invalid":
+			return http.StatusBadRequest
+		default:
+			return http.StatusCreated
+		}
+	})
+	s := newTestElastic(t, es.URL, nil)
+
+	for _, message := range []string{"throttled", "indexed", "invalid"} {
+		s.Write(types.LogEntry{Level: types.Info, Message: message})
+	}
+
+	err := s.Flush()
+	if err == nil || !strings.Contains(err.Error(), "1 of 3 documents") {
+		t.Fatalf("Flush returned %v, want 1 of 3 documents dead-lettered", err)
+	}
+
+	es.mu.Lock()
+	requests := es.bulks
+	es.mu.Unlock()
+
+	if len(requests) != 2 || strings.Join(requests[1], ",") != "throttled" {
+		t.Fatalf("got requests %q, want the throttled item retried alone", requests)
+	}
+
+	letters := readDeadLetters(t, s.deadLetter)
+	if len(letters) != 1 {
+		t.Fatalf("got %d dead letters, want 1", len(letters))
+	}
+	if letters[0].Status != http.StatusBadRequest || !strings.Contains(string(letters[0].Error), "test_exception") {
+		t.Errorf("dead letter misses the item status or error: %+v", letters[0])
+	}
+	if !strings.HasPrefix(letters[0].Index, "vloggo-test_app-") || !strings.Contains(string(letters[0].Document), `"message":"invalid"`) {
+		t.Errorf("dead letter misses the index or document: %+v", letters[0])
+	}
+}
+
+func TestElasticDeadLettersAfterRetries(t *testing.T) {
+	es := newElasticServer(t, func(int, string) int { return 0 })
+	s := newTestElastic(t, es.URL, func(cfg *types.Elastic) {
+		cfg.Retries = 1
+	})
+
+	s.Write(types.LogEntry{Level: types.Error, Message: "unavailable"})
+	if err := s.Flush(); err == nil {
+		t.Fatal("Flush succeeded while the server was failing")
+	}
+
+	es.mu.Lock()
+	requests := es.requests
+	es.mu.Unlock()
+	if requests != 2 {
+		t.Errorf("got %d requests, want 2 with 1 retry", requests)
+	}
+
+	letters := readDeadLetters(t, s.deadLetter)
+	if len(letters) != 1 || !strings.Contains(string(letters[0].Error), "503") {
+		t.Fatalf("got dead letters %+v, want the document with the last error", letters)
+	}
+}
+
+func TestElasticDeadLetterInvalidDocument(t *testing.T) {
+	s := newTestElastic(t, "http://127.0.0.1:1", nil)
+
+	err := s.writeDeadLetters([]deadLetter{{
+		Index:    "vloggo-test-2026.10.16",
+		Error:    json.RawMessage(`"document is not valid JSON"`),
+		Document: json.RawMessage("[VLoggo] > failed to serialize log"),
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	letters := readDeadLetters(t, s.deadLetter)
+	if len(letters) != 1 {
+		t.Fatalf("got %d dead letters, want 1", len(letters))
+	}
+
+	var document string
+	if err := json.Unmarshal(letters[0].Document, &document); err != nil || document != "[VLoggo] > failed to serialize log" {
+		t.Fatalf("got document %s, want it as a string", letters[0].Document)
+	}
+}
+
+func TestElasticDeadLetterOutsideRetention(t *testing.T) {
+	fs := newTestFileService(t, func(cfg *types.VLoggoConfig) {
+		cfg.Json = true
+		cfg.Retention.MaxAge = 48 * time.Hour
+	})
+
+	s := NewElasticSink(types.Elastic{URL: "http://127.0.0.1:1", Interval: time.Hour}, "test", fs.cfg.Directory.Json)
+	defer s.Close()
+
+	if filepath.Dir(filepath.Dir(s.deadLetter)) != fs.cfg.Directory.Json {
+		t.Fatalf("dead letter file %s not in a folder under %s", s.deadLetter, fs.cfg.Directory.Json)
+	}
+
+	if err := s.writeDeadLetters([]deadLetter{{Document: json.RawMessage(`{}`)}}); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-72 * time.Hour)
+	if err := os.Chtimes(s.deadLetter, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	fs.mu.Lock()
+	err := fs.rotateJson()
+	fs.mu.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(s.deadLetter); err != nil {
+		t.Fatalf("retention removed the dead letter file > %v", err)
+	}
+}
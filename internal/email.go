@@ -1 +1,354 @@
-package services
\ No newline at end of file
+package services
+
+import (
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	types "github.com/vinialx/vloggo-go/types"
+)
+
+// smtpTimeout limits how long a single notification may take to be delivered
+const smtpTimeout = 10 * time.Second
+
+// EmailService manages error notifications sent by email
+// Handles SMTP delivery in the background and throttling between emails based on cfg.Throttle
+// When cfg.Digest is enabled, entries arriving during the throttle window are grouped into one email
+type EmailService struct {
+	cfg      types.VLoggoConfig
+	format   *FormatService
+	lastSent time.Time
+
+	pending []*digestEntry
+	timer   *time.Timer
+
+	wg sync.WaitGroup
+	mu sync.Mutex
+}
+
+// digestEntry groups every occurrence of a code waiting to be sent in a digest
+type digestEntry struct {
+	level types.LogLevel
+	code  string
+	line  string
+	count int
+	first time.Time
+	last  time.Time
+}
+
+// NewEmailService creates a new EmailService instance
+// Returns an EmailService ready to send notifications
+func NewEmailService(cfg types.VLoggoConfig) *EmailService {
+	return &EmailService{
+		cfg:    cfg,
+		format: NewFormatService(cfg.Client),
+	}
+}
+
+// Update replaces the configuration used for the next notifications
+// Pending digest entries are sent right away if digest mode is turned off
+func (es *EmailService) Update(cfg types.VLoggoConfig) {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+
+	es.cfg = cfg
+	es.format = NewFormatService(cfg.Client)
+
+	if !cfg.Digest {
+		es.flushDigest()
+	}
+}
+
+// Notify sends an email for the log entry if notifications are enabled
+// Only ERROR and FATAL entries are sent, at most one email every cfg.Throttle seconds
+// FATAL entries are never throttled, since the process exits right after them
+// In digest mode entries inside the throttle window are queued and FATAL entries flush the queue at once
+// Delivery happens in a separate goroutine so the logging call never blocks
+func (es *EmailService) Notify(entry types.LogEntry) {
+	if entry.Level != types.Error && entry.Level != types.Fatal {
+		return
+	}
+
+	es.mu.Lock()
+	defer es.mu.Unlock()
+
+	if !es.cfg.Notify {
+		return
+	}
+
+	now := time.Now()
+	throttle := time.Duration(es.cfg.Throttle) * time.Second
+	wait := throttle - now.Sub(es.lastSent)
+
+	if !es.cfg.Digest {
+		if entry.Level != types.Fatal && !es.lastSent.IsZero() && wait > 0 {
+			return
+		}
+
+		es.lastSent = now
+		es.dispatch(
+			fmt.Sprintf("[%s] %s %s", es.format.Client, entry.Level, entry.Code),
+			es.format.Line(entry),
+		)
+		return
+	}
+
+	es.collect(entry)
+
+	if entry.Level == types.Fatal || es.lastSent.IsZero() || wait <= 0 {
+		es.flushDigest()
+		return
+	}
+
+	if es.timer == nil {
+		es.timer = time.AfterFunc(wait, func() {
+			es.mu.Lock()
+			defer es.mu.Unlock()
+
+			es.timer = nil
+			es.flushDigest()
+		})
+	}
+}
+
+// Flush sends the pending digest immediately and waits until every notification is sent
+func (es *EmailService) Flush() {
+	es.mu.Lock()
+	es.flushDigest()
+	es.mu.Unlock()
+
+	es.Wait()
+}
+
+// Wait blocks until all notifications in progress have been sent
+// Used before the process exits so FATAL notifications are not lost
+func (es *EmailService) Wait() {
+	es.wg.Wait()
+}
+
+// collect adds an entry to the pending digest, grouping it by code
+// The digest uses the time the entry was logged, which in async mode can be well before Notify runs
+// Must be called with es.mu held
+func (es *EmailService) collect(entry types.LogEntry) {
+	now := entry.Time
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	for _, d := range es.pending {
+		if d.code == entry.Code {
+			d.count++
+			d.last = now
+			if entry.Level == types.Fatal {
+				d.level = types.Fatal
+			}
+			return
+		}
+	}
+
+	es.pending = append(es.pending, &digestEntry{
+		level: entry.Level,
+		code:  entry.Code,
+		line:  es.format.Line(entry),
+		count: 1,
+		first: now,
+		last:  now,
+	})
+}
+
+// flushDigest sends the pending digest, if any, and resets the throttle window
+// Must be called with es.mu held
+func (es *EmailService) flushDigest() {
+	if es.timer != nil {
+		es.timer.Stop()
+		es.timer = nil
+	}
+
+	if len(es.pending) == 0 {
+		return
+	}
+
+	pending := es.pending
+	es.pending = nil
+	es.lastSent = time.Now()
+
+	if len(pending) == 1 && pending[0].count == 1 {
+		es.dispatch(
+			fmt.Sprintf("[%s] %s %s", es.format.Client, pending[0].level, pending[0].code),
+			pending[0].line,
+		)
+		return
+	}
+
+	es.dispatch(es.digest(pending))
+}
+
+// digest builds the subject and body of a digest email
+// The body lists each code with its count, first and last occurrence and first log line
+func (es *EmailService) digest(pending []*digestEntry) (string, string) {
+	level := types.Error
+	total := 0
+	first, last := pending[0].first, pending[0].last
+	for _, d := range pending {
+		total += d.count
+		if d.level == types.Fatal {
+			level = types.Fatal
+		}
+		if d.first.Before(first) {
+			first = d.first
+		}
+		if d.last.After(last) {
+			last = d.last
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d entries from %s between %s and %s\n\n",
+		total,
+		es.format.Client,
+		es.format.Date(first),
+		es.format.Date(last),
+	)
+
+	for _, d := range pending {
+		fmt.Fprintf(&b, "[%s] [%s] x%d : first %s > last %s\n%s\n",
+			d.level,
+			d.code,
+			d.count,
+			es.format.Date(d.first),
+			es.format.Date(d.last),
+			d.line,
+		)
+	}
+
+	subject := fmt.Sprintf("[%s] %s digest > %d entries", es.format.Client, level, total)
+	return subject, b.String()
+}
+
+// dispatch sends an email in a separate goroutine
+// Must be called with es.mu held
+func (es *EmailService) dispatch(subject, body string) {
+	smtpCfg := es.cfg.SMTP
+	format := es.format
+
+	es.wg.Add(1)
+	go func() {
+		defer es.wg.Done()
+		defer func() {
+			if r := recover(); r != nil {
+				fmt.Printf("[VLoggo] > [%s] [%s] [ERROR] : email notification panicked > %v\n",
+					format.Client,
+					format.Date(),
+					r,
+				)
+			}
+		}()
+
+		if err := es.send(smtpCfg, subject, body); err != nil {
+			fmt.Printf("[VLoggo] > [%s] [%s] [ERROR] : failed to send email notification > %v\n",
+				format.Client,
+				format.Date(),
+				err,
+			)
+		}
+	}()
+}
+
+// header makes value safe to write as a header field body
+// Control characters, CR and LF included, become spaces so they cannot start a new header,
+// and non-ASCII text is encoded with mime.QEncoding
+func header(value string) string {
+	value = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}, value)
+
+	return mime.QEncoding.Encode("utf-8", value)
+}
+
+// message builds a plain text email with the headers required by RFC 5322
+func (es *EmailService) message(cfg types.VLoggoSMTP, subject, body string) []byte {
+	var b strings.Builder
+
+	b.WriteString("From: " + cfg.From + "\r\n")
+	b.WriteString("To: " + strings.Join(cfg.To, ", ") + "\r\n")
+	b.WriteString("Subject: " + header(subject) + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+
+	return []byte(b.String())
+}
+
+// send delivers a single email through the configured SMTP server
+// Uses STARTTLS and PLAIN authentication when the server advertises them
+func (es *EmailService) send(cfg types.VLoggoSMTP, subject, body string) error {
+	addr := net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))
+
+	conn, err := net.DialTimeout("tcp", addr, smtpTimeout)
+	if err != nil {
+		return fmt.Errorf("error connecting to smtp server > %w", err)
+	}
+
+	if err := conn.SetDeadline(time.Now().Add(smtpTimeout)); err != nil {
+		conn.Close()
+		return fmt.Errorf("error setting smtp deadline > %w", err)
+	}
+
+	c, err := smtp.NewClient(conn, cfg.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("error creating smtp client > %w", err)
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: cfg.Host}); err != nil {
+			return fmt.Errorf("error starting tls > %w", err)
+		}
+	}
+
+	if ok, _ := c.Extension("AUTH"); ok && cfg.Username != "" {
+		auth := smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+		if err := c.Auth(auth); err != nil {
+			return fmt.Errorf("error authenticating > %w", err)
+		}
+	}
+
+	if err := c.Mail(cfg.From); err != nil {
+		return fmt.Errorf("error setting sender > %w", err)
+	}
+
+	for _, to := range cfg.To {
+		if err := c.Rcpt(to); err != nil {
+			return fmt.Errorf("error setting recipient %s > %w", to, err)
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("error starting data > %w", err)
+	}
+
+	if _, err := w.Write(es.message(cfg, subject, body)); err != nil {
+		w.Close()
+		return fmt.Errorf("error writing message > %w", err)
+	}
+
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("error finishing message > %w", err)
+	}
+
+	return c.Quit()
+}
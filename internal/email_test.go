@@ -0,0 +1,335 @@
+package services
+
+import (
+	"bufio"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	types "github.com/vinialx/vloggo-go/types"
+)
+
+// smtpServer starts an in-process SMTP server accepting every message
+// Returns the config pointing at it and a channel receiving each message
+func smtpServer(t *testing.T) (types.VLoggoConfig, <-chan string) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	messages := make(chan string, 16)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go smtpSession(conn, messages)
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	p, _ := strconv.Atoi(port)
+
+	cfg := types.VLoggoConfig{
+		Client: "test",
+		Notify: true,
+		SMTP: types.VLoggoSMTP{
+			Host: host,
+			Port: p,
+			From: "vloggo@example.com",
+			To:   []string{"ops@example.com"},
+		},
+	}
+
+	return cfg, messages
+}
+
+// smtpSession answers a single SMTP session, sending the DATA of each message to messages
+func smtpSession(conn net.Conn, messages chan<- string) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	reply("220 localhost ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+
+		switch cmd := strings.ToUpper(strings.TrimSpace(line)); {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(cmd, "DATA"):
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(line)
+			}
+			messages <- data.String()
+			reply("250 queued")
+		case strings.HasPrefix(cmd, "QUIT"):
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+// received returns the messages delivered so far
+func received(messages <-chan string) []string {
+	var out []string
+	for {
+		select {
+		case m := <-messages:
+			out = append(out, m)
+		default:
+			return out
+		}
+	}
+}
+
+func TestNotifySendsErrorEntries(t *testing.T) {
+	cfg, messages := smtpServer(t)
+	es := NewEmailService(cfg)
+
+	es.Notify(types.LogEntry{Level: types.Info, Code: "BOOT", Message: "started"})
+	es.Notify(types.LogEntry{Level: types.Error, Code: "DB", Message: "connection lost"})
+	es.Flush()
+
+	got := received(messages)
+	if len(got) != 1 {
+		t.Fatalf("got %d emails, want 1", len(got))
+	}
+	if !strings.Contains(got[0], "Subject: [test] ERROR DB") {
+		t.Errorf("missing subject in %q", got[0])
+	}
+	if !strings.Contains(got[0], "connection lost") {
+		t.Errorf("missing log line in %q", got[0])
+	}
+}
+
+func TestNotifyDisabled(t *testing.T) {
+	cfg, messages := smtpServer(t)
+	cfg.Notify = false
+	es := NewEmailService(cfg)
+
+	es.Notify(types.LogEntry{Level: types.Error, Code: "DB", Message: "connection lost"})
+	es.Flush()
+
+	if got := received(messages); len(got) != 0 {
+		t.Fatalf("got %d emails, want 0", len(got))
+	}
+}
+
+func TestNotifyThrottle(t *testing.T) {
+	cfg, messages := smtpServer(t)
+	cfg.Throttle = 60
+	es := NewEmailService(cfg)
+
+	es.Notify(types.LogEntry{Level: types.Error, Code: "A", Message: "first"})
+	es.Notify(types.LogEntry{Level: types.Error, Code: "B", Message: "second"})
+	es.Flush()
+
+	got := received(messages)
+	if len(got) != 1 {
+		t.Fatalf("got %d emails, want 1", len(got))
+	}
+	if !strings.Contains(got[0], "first") {
+		t.Errorf("throttle kept the wrong email: %q", got[0])
+	}
+}
+
+func TestNotifyFatalBypassesThrottle(t *testing.T) {
+	cfg, messages := smtpServer(t)
+	cfg.Throttle = 60
+	es := NewEmailService(cfg)
+
+	es.Notify(types.LogEntry{Level: types.Error, Code: "A", Message: "first"})
+	es.Notify(types.LogEntry{Level: types.Fatal, Code: "CRASH", Message: "giving up"})
+	es.Wait()
+
+	got := received(messages)
+	if len(got) != 2 {
+		t.Fatalf("got %d emails, want 2", len(got))
+	}
+
+	var fatal bool
+	for _, m := range got {
+		fatal = fatal || strings.Contains(m, "Subject: [test] FATAL CRASH")
+	}
+	if !fatal {
+		t.Errorf("FATAL email not sent: %q", got)
+	}
+}
+
+func TestNotifyUnreachableServer(t *testing.T) {
+	cfg, _ := smtpServer(t)
+	cfg.SMTP.Port = 1
+	es := NewEmailService(cfg)
+
+	done := make(chan struct{})
+	go func() {
+		es.Notify(types.LogEntry{Level: types.Error, Code: "DB", Message: "connection lost"})
+		es.Flush()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * smtpTimeout):
+		t.Fatal("Flush did not return")
+	}
+}
+
+func TestDigestGroupsByCode(t *testing.T) {
+	cfg, messages := smtpServer(t)
+	cfg.Throttle = 60
+	cfg.Digest = true
+	es := NewEmailService(cfg)
+
+	es.Notify(types.LogEntry{Level: types.Error, Code: "BOOT", Message: "sent right away"})
+	es.Wait()
+	if got := received(messages); len(got) != 1 {
+		t.Fatalf("got %d emails before the digest, want 1", len(got))
+	}
+
+	start := time.Date(2026, 10, 16, 10, 0, 0, 0, time.Local)
+	es.Notify(types.LogEntry{Time: start, Level: types.Error, Code: "DB", Message: "connection lost"})
+	es.Notify(types.LogEntry{Time: start.Add(time.Minute), Level: types.Error, Code: "CACHE", Message: "miss storm"})
+	es.Notify(types.LogEntry{Time: start.Add(2 * time.Minute), Level: types.Error, Code: "DB", Message: "connection lost again"})
+	es.Notify(types.LogEntry{Time: start.Add(3 * time.Minute), Level: types.Error, Code: "DB", Message: "still lost"})
+	es.Flush()
+
+	got := received(messages)
+	if len(got) != 1 {
+		t.Fatalf("got %d digest emails, want 1", len(got))
+	}
+
+	digest := got[0]
+	if !strings.Contains(digest, "Subject: [test] ERROR digest > 4 entries") {
+		t.Errorf("missing digest subject in %q", digest)
+	}
+	if !strings.Contains(digest, "4 entries from test between 16/10/2026 10:00:00 and 16/10/2026 10:03:00") {
+		t.Errorf("missing digest summary in %q", digest)
+	}
+	if !strings.Contains(digest, "[ERROR] [DB] x3 : first 16/10/2026 10:00:00 > last 16/10/2026 10:03:00") {
+		t.Errorf("DB not grouped in %q", digest)
+	}
+	if !strings.Contains(digest, "[ERROR] [CACHE] x1 : first 16/10/2026 10:01:00 > last 16/10/2026 10:01:00") {
+		t.Errorf("CACHE not grouped in %q", digest)
+	}
+	if !strings.Contains(digest, "connection lost") || strings.Contains(digest, "still lost") {
+		t.Errorf("digest should keep the first line of each code: %q", digest)
+	}
+	if strings.Index(digest, "[DB]") > strings.Index(digest, "[CACHE]") {
+		t.Errorf("codes not listed in arrival order: %q", digest)
+	}
+}
+
+func TestDigestWaitsForThrottle(t *testing.T) {
+	cfg, messages := smtpServer(t)
+	cfg.Throttle = 60
+	cfg.Digest = true
+	es := NewEmailService(cfg)
+
+	es.Notify(types.LogEntry{Level: types.Error, Code: "A", Message: "first"})
+	es.Notify(types.LogEntry{Level: types.Error, Code: "B", Message: "second"})
+	es.Notify(types.LogEntry{Level: types.Error, Code: "B", Message: "third"})
+	es.Wait()
+
+	if got := received(messages); len(got) != 1 {
+		t.Fatalf("got %d emails inside the throttle window, want 1", len(got))
+	}
+
+	es.Flush()
+
+	got := received(messages)
+	if len(got) != 1 {
+		t.Fatalf("got %d digest emails, want 1", len(got))
+	}
+	if !strings.Contains(got[0], "Subject: [test] ERROR digest > 2 entries") || !strings.Contains(got[0], "[B] x2") {
+		t.Errorf("pending entries not sent as a digest: %q", got[0])
+	}
+}
+
+func TestDigestFatalFlushesPending(t *testing.T) {
+	cfg, messages := smtpServer(t)
+	cfg.Throttle = 60
+	cfg.Digest = true
+	es := NewEmailService(cfg)
+
+	es.Notify(types.LogEntry{Level: types.Error, Code: "A", Message: "first"})
+	es.Notify(types.LogEntry{Level: types.Error, Code: "B", Message: "second"})
+	es.Notify(types.LogEntry{Level: types.Fatal, Code: "CRASH", Message: "giving up"})
+	es.Wait()
+
+	got := received(messages)
+	if len(got) != 2 {
+		t.Fatalf("got %d emails, want 2", len(got))
+	}
+
+	var digest string
+	for _, m := range got {
+		if strings.Contains(m, "digest") {
+			digest = m
+		}
+	}
+	if !strings.Contains(digest, "Subject: [test] FATAL digest > 2 entries") {
+		t.Fatalf("FATAL did not flush the pending digest: %q", got)
+	}
+	if !strings.Contains(digest, "[ERROR] [B] x1") || !strings.Contains(digest, "[FATAL] [CRASH] x1") {
+		t.Errorf("digest misses pending entries: %q", digest)
+	}
+
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	if len(es.pending) != 0 || es.timer != nil {
+		t.Error("digest still pending after FATAL")
+	}
+}
+
+func TestNotifySubjectHeader(t *testing.T) {
+	cfg, messages := smtpServer(t)
+	es := NewEmailService(cfg)
+
+	es.Notify(types.LogEntry{Level: types.Error, Code: "DB\r\nBcc: attacker@example.com", Message: "injected"})
+	es.Flush()
+
+	got := received(messages)
+	if len(got) != 1 {
+		t.Fatalf("got %d emails, want 1", len(got))
+	}
+	headers, _, _ := strings.Cut(got[0], "\r\n\r\n")
+	if strings.Contains(headers, "\r\nBcc:") {
+		t.Fatalf("code injected a header: %q", headers)
+	}
+	if !strings.Contains(headers, "Subject: [test] ERROR DB  Bcc: attacker@example.com\r\n") {
+		t.Errorf("control characters not replaced in %q", got[0])
+	}
+
+	cfg.Client = "café"
+	es.Update(cfg)
+	es.Notify(types.LogEntry{Level: types.Fatal, Code: "CRASH", Message: "accented"})
+	es.Wait()
+
+	got = received(messages)
+	if len(got) != 1 || !strings.Contains(got[0], "Subject: =?utf-8?q?[caf=C3=A9]_FATAL_CRASH?=\r\n") {
+		t.Errorf("non-ASCII subject not Q-encoded: %q", got)
+	}
+}
@@ -1,41 +1,143 @@
-// Package services provides formatting and file management services for VLoggo.
-// Includes FormatService for log formatting and timestamps, and FileService for file operations,
-// log rotation and retention management.
 package services
 
 import (
+	"bufio"
 	"fmt"
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	types "github.com/vinialx/vloggo-go/types"
 )
 
+// defaultBufferSize is the buffer size used when cfg.Flush.Size is not set
+const defaultBufferSize = 32 * 1024
+
 // FileService manages log file operations
 // Handles file creation, writing, rotation, and cleanup based on retention policies
+// The current files are kept open behind buffered writers and flushed according to cfg.Flush
 type FileService struct {
-	cfg          types.VLoggoConfig
-	txtFilename  string
-	jsonFilename string
+	cfg  types.VLoggoConfig
+	txt  *logFile
+	json *logFile
 
-	currentDay  int
+	period      string
 	format      *FormatService
 	initialized bool
+	closed      bool
+	done        chan struct{}
+	compressing map[string]bool
+	hooking     map[string]int
+	released    map[string]int64
+	hooks       []func(oldPath, newPath string) error
+	wg          sync.WaitGroup
 	mu          sync.Mutex
 }
 
+// logFile is an open log file with a buffered writer in front of it
+// size tracks the bytes in the file, including buffered ones, for size-based rotation
+type logFile struct {
+	path   string
+	index  int
+	size   int64
+	file   *os.File
+	writer *bufio.Writer
+}
+
+// openLogFile opens a file for appending, creating it if it doesn't exist
+func openLogFile(path string, size int) (*logFile, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return &logFile{
+		path:   path,
+		size:   info.Size(),
+		file:   f,
+		writer: bufio.NewWriterSize(f, size),
+	}, nil
+}
+
+// write appends content to the buffered writer and updates the file size
+// The buffer is flushed before content that does not fit in it, and content larger than the
+// whole buffer is written directly, so every write to the file ends on a line boundary
+// and lines from other writers appending to the same file never land inside ours
+func (lf *logFile) write(content string) error {
+	if len(content) > lf.writer.Available() && lf.writer.Buffered() > 0 {
+		if err := lf.writer.Flush(); err != nil {
+			return err
+		}
+	}
+
+	var n int
+	var err error
+	if len(content) > lf.writer.Available() {
+		n, err = lf.file.WriteString(content)
+	} else {
+		n, err = lf.writer.WriteString(content)
+	}
+
+	lf.size += int64(n)
+	return err
+}
+
+// full reports whether writing n more bytes would exceed maxSize
+// An empty file is never full, so a single large line still gets written
+func (lf *logFile) full(n int, maxSize int64) bool {
+	return maxSize > 0 && lf.size > 0 && lf.size+int64(n) > maxSize
+}
+
+// close flushes the buffered content and closes the file
+func (lf *logFile) close() error {
+	if lf == nil {
+		return nil
+	}
+
+	flushErr := lf.writer.Flush()
+	if err := lf.file.Close(); err != nil {
+		return err
+	}
+
+	return flushErr
+}
+
+// flush writes the buffered content to the file
+func (lf *logFile) flush() error {
+	if lf == nil {
+		return nil
+	}
+
+	return lf.writer.Flush()
+}
+
 // NewFileService creates a new FileService instance and initializes it automatically
+// Starts a background flush every cfg.Flush.Interval when set
 // Returns a FileService ready to write logs
 func NewFileService(cfg types.VLoggoConfig) *FileService {
 	fs := &FileService{
 		cfg:         cfg,
 		format:      NewFormatService(cfg.Client),
-		currentDay:  0,
+		period:      "",
 		initialized: false,
+		done:        make(chan struct{}),
+		compressing: make(map[string]bool),
+		hooking:     make(map[string]int),
+		released:    make(map[string]int64),
 	}
+	fs.format.Location = cfg.Rollover.Location
+	fs.format.Interval = cfg.Rollover.Interval
+	fs.format.TxtTemplate = cfg.Templates.Txt
+	fs.format.JSONTemplate = cfg.Templates.Json
 
 	if err := fs.Initialize(); err != nil {
 		fmt.Printf("[VLoggo] > [%s] [%s] [ERROR] : failed to initialize FileService > %v\n",
@@ -45,44 +147,76 @@ func NewFileService(cfg types.VLoggoConfig) *FileService {
 		)
 	}
 
-	return fs
-}
-
-// appendToFile appends content to a file, creating it if it doesn't exist
-func (fs *FileService) appendToFile(filename, content string) error {
-	f, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		return err
+	if cfg.Flush.Interval > 0 {
+		go fs.flushLoop(cfg.Flush.Interval)
 	}
 
-	defer f.Close()
+	if cfg.Rollover.Timer {
+		go fs.rolloverLoop()
+	}
 
-	_, err = f.WriteString(content)
-	return err
+	return fs
 }
 
-// Initialize initializes the file service by creating log directories and first log file
-// Sets up current day tracking for rotation purposes
-// This method is idempotent - calling it multiple times has no effect after first initialization
-func (fs *FileService) Initialize() error {
-	fs.mu.Lock()
-	defer fs.mu.Unlock()
+// rolloverLoop rolls the log files over at the start of every period until the service is closed
+// Keeps file creation and retention cleanup on time even when nothing is logged
+func (fs *FileService) rolloverLoop() {
+	for {
+		timer := time.NewTimer(time.Until(fs.format.NextPeriod(time.Now())))
+
+		select {
+		case <-fs.done:
+			timer.Stop()
+			return
+		case <-timer.C:
+			fs.mu.Lock()
+			err := fs.verify()
+			fs.mu.Unlock()
 
-	if fs.initialized {
-		return nil
+			if err != nil {
+				fmt.Printf("[VLoggo] > [%s] [%s] [ERROR] : scheduled rollover failed > %v\n",
+					fs.cfg.Client,
+					fs.format.Date(),
+					err,
+				)
+			}
+		}
 	}
+}
 
-	fs.currentDay = time.Now().Day()
+// flushLoop flushes the buffered writers every interval until the service is closed
+func (fs *FileService) flushLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-fs.done:
+			return
+		case <-ticker.C:
+			if err := fs.Flush(); err != nil {
+				fmt.Printf("[VLoggo] > [%s] [%s] [ERROR] : failed to flush log files > %v\n",
+					fs.cfg.Client,
+					fs.format.Date(),
+					err,
+				)
+			}
+		}
+	}
+}
 
+// open creates the log directories and opens the log files for the current date
+// Continues from the last size-rotated file of the day, if any
+// Writes the separator to each new file and closes the previously open files
+// Must be called with fs.mu held
+func (fs *FileService) open() error {
 	txtDir := fs.cfg.Directory.Txt
 	if err := os.MkdirAll(txtDir, 0755); err != nil {
 		return fmt.Errorf("error creating txt directory > %s", err)
 	}
 
-	fs.txtFilename = filepath.Join(txtDir, fs.format.Filename())
-
-	if err := fs.appendToFile(fs.txtFilename, fs.format.Separator()); err != nil {
-		return fmt.Errorf("error writing txt separator: %w", err)
+	if err := fs.openTxt(lastIndex(txtDir, fs.format.Filename)); err != nil {
+		return err
 	}
 
 	if fs.cfg.Json {
@@ -91,12 +225,222 @@ func (fs *FileService) Initialize() error {
 			return fmt.Errorf("error creating json directory > %s", err)
 		}
 
-		fs.jsonFilename = filepath.Join(jsonDir, fs.format.JSONFilename())
+		if err := fs.openJson(lastIndex(jsonDir, fs.format.JSONFilename)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// lastIndex returns the size-rotation index to continue writing to in dir
+// This is the highest index with an existing file, or the next one if that file was already compressed
+// Returns 0 when no file exists for the current date
+func lastIndex(dir string, filename func(index ...int) string) int {
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(dir, name))
+		return err == nil
+	}
+
+	index := 0
+	for exists(filename(index+1)) || exists(filename(index+1)+gzipExt) {
+		index++
+	}
+
+	if !exists(filename(index)) && exists(filename(index)+gzipExt) {
+		return index + 1
+	}
+
+	return index
+}
+
+// bufferSize returns the buffered writer size from cfg.Flush.Size
+func (fs *FileService) bufferSize() int {
+	if fs.cfg.Flush.Size <= 0 {
+		return defaultBufferSize
+	}
+	return fs.cfg.Flush.Size
+}
+
+// openTxt opens the txt log file with the given size-rotation index and closes the previous one
+// Must be called with fs.mu held
+func (fs *FileService) openTxt(index int) error {
+	txtFile, err := openLogFile(filepath.Join(fs.cfg.Directory.Txt, fs.format.Filename(index)), fs.bufferSize())
+	if err != nil {
+		return fmt.Errorf("error opening txt file > %w", err)
+	}
+	txtFile.index = index
+
+	previous := fs.txt
+	if err := previous.close(); err != nil {
+		fmt.Printf("[VLoggo] > [%s] [%s] [ERROR] : error closing txt file > %v\n",
+			fs.cfg.Client,
+			fs.format.Date(),
+			err,
+		)
+	}
+	fs.txt = txtFile
+
+	if previous != nil && previous.path != txtFile.path {
+		fs.released[previous.path] = previous.size
+		fs.rotated(previous.path, txtFile.path)
+	}
+
+	if fs.cfg.Templates.Current {
+		fs.link(txtFile.path)
+	}
+
+	if err := fs.txt.write(fs.format.Separator()); err != nil {
+		return fmt.Errorf("error writing txt separator > %w", err)
+	}
+
+	return nil
+}
+
+// openJson opens the json log file with the given size-rotation index and closes the previous one
+// Must be called with fs.mu held
+func (fs *FileService) openJson(index int) error {
+	jsonFile, err := openLogFile(filepath.Join(fs.cfg.Directory.Json, fs.format.JSONFilename(index)), fs.bufferSize())
+	if err != nil {
+		return fmt.Errorf("error opening json file > %w", err)
+	}
+	jsonFile.index = index
+
+	previous := fs.json
+	if err := previous.close(); err != nil {
+		fmt.Printf("[VLoggo] > [%s] [%s] [ERROR] : error closing json file > %v\n",
+			fs.cfg.Client,
+			fs.format.Date(),
+			err,
+		)
+	}
+	fs.json = jsonFile
+
+	if previous != nil && previous.path != jsonFile.path {
+		fs.released[previous.path] = previous.size
+		fs.rotated(previous.path, jsonFile.path)
+	}
+
+	if fs.cfg.Templates.Current {
+		fs.link(jsonFile.path)
+	}
+
+	if err := fs.json.write(fs.format.JSONSeparator()); err != nil {
+		return fmt.Errorf("error writing json separator > %w", err)
+	}
+
+	return nil
+}
+
+// OnRotate registers a hook called after the service switches from oldPath to newPath
+// Hooks run in the background, in registration order, after oldPath has been flushed and closed
+// Errors and panics are reported on the console. Compression and retention deletion of oldPath
+// wait until every hook for it has returned
+func (fs *FileService) OnRotate(hook func(oldPath, newPath string) error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	fs.hooks = append(fs.hooks, hook)
+}
+
+// rotated runs the rotation hooks for oldPath in the background and then compresses it
+// Must be called with fs.mu held
+func (fs *FileService) rotated(oldPath, newPath string) {
+	if len(fs.hooks) == 0 {
+		fs.compress(oldPath)
+		return
+	}
+
+	hooks := append([]func(oldPath, newPath string) error(nil), fs.hooks...)
+	fs.hooking[oldPath]++
+
+	fs.wg.Add(1)
+	go func() {
+		defer fs.wg.Done()
+
+		for _, hook := range hooks {
+			fs.runHook(hook, oldPath, newPath)
+		}
+
+		fs.mu.Lock()
+		defer fs.mu.Unlock()
 
-		if err := fs.appendToFile(fs.jsonFilename, fs.format.JSONSeparator()); err != nil {
-			return fmt.Errorf("error writing json separator: %w", err)
+		fs.hooking[oldPath]--
+		if fs.hooking[oldPath] <= 0 {
+			delete(fs.hooking, oldPath)
+			fs.compress(oldPath)
 		}
+	}()
+}
+
+// runHook calls a rotation hook, reporting its error or panic
+func (fs *FileService) runHook(hook func(oldPath, newPath string) error, oldPath, newPath string) {
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Printf("[VLoggo] > [%s] [%s] [ERROR] : rotation hook panicked > %v\n",
+				fs.cfg.Client,
+				fs.format.Date(),
+				r,
+			)
+		}
+	}()
+
+	if err := hook(oldPath, newPath); err != nil {
+		fmt.Printf("[VLoggo] > [%s] [%s] [ERROR] : rotation hook failed for %s > %v\n",
+			fs.cfg.Client,
+			fs.format.Date(),
+			oldPath,
+			err,
+		)
+	}
+}
+
+// link points the "current" symlink next to path at it, named current plus the extension of path
+// The link is replaced atomically so readers never see it missing
+// Must be called with fs.mu held
+func (fs *FileService) link(path string) {
+	dir, name := filepath.Split(path)
+	current := filepath.Join(dir, "current"+filepath.Ext(name))
+	tmp := current + ".tmp"
+
+	os.Remove(tmp)
+	if err := os.Symlink(name, tmp); err != nil {
+		fmt.Printf("[VLoggo] > [%s] [%s] [ERROR] : error creating current symlink > %v\n",
+			fs.cfg.Client,
+			fs.format.Date(),
+			err,
+		)
+		return
+	}
+
+	if err := os.Rename(tmp, current); err != nil {
+		os.Remove(tmp)
+		fmt.Printf("[VLoggo] > [%s] [%s] [ERROR] : error replacing current symlink > %v\n",
+			fs.cfg.Client,
+			fs.format.Date(),
+			err,
+		)
 	}
+}
+
+// Initialize initializes the file service by creating log directories and first log file
+// Sets up current period tracking for rotation purposes
+// This method is idempotent - calling it multiple times has no effect after first initialization
+func (fs *FileService) Initialize() error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if fs.initialized {
+		return nil
+	}
+
+	fs.period = fs.format.Period(time.Now())
+
+	if err := fs.open(); err != nil {
+		return err
+	}
+
+	fs.compressRotated()
 
 	fs.initialized = true
 
@@ -109,15 +453,17 @@ func (fs *FileService) Initialize() error {
 
 }
 
-// verify checks if log rotation is needed by checking if the day has changed
+// verify checks if log rotation is needed by checking if the rotation period has changed
 // If rotation is needed, creates a new log file and triggers cleanup of old files
+// Must be called with fs.mu held
 func (fs *FileService) verify() error {
-	today := time.Now().Day()
+	if fs.closed || !fs.initialized {
+		return nil
+	}
 
-	fs.mu.Lock()
-	defer fs.mu.Unlock()
+	period := fs.format.Period(time.Now())
 
-	if today == fs.currentDay {
+	if period == fs.period {
 		return nil
 	}
 
@@ -128,33 +474,15 @@ func (fs *FileService) verify() error {
 		)
 	}
 
-	fs.currentDay = time.Now().Day()
-
-	txtDir := fs.cfg.Directory.Txt
-	if err := os.MkdirAll(txtDir, 0755); err != nil {
-		return fmt.Errorf("error creating txt directory > %s", err)
-	}
-
-	fs.txtFilename = filepath.Join(txtDir, fs.format.Filename())
-
-	if err := fs.appendToFile(fs.txtFilename, fs.format.Separator()); err != nil {
-		return fmt.Errorf("error writing txt separator > %w", err)
-	}
-
-	if fs.cfg.Json {
-		jsonDir := fs.cfg.Directory.Json
-		if err := os.MkdirAll(jsonDir, 0755); err != nil {
-			return fmt.Errorf("error creating json directory > %s", err)
-		}
+	fs.period = period
+	fs.released = make(map[string]int64)
 
-		fs.jsonFilename = filepath.Join(jsonDir, fs.format.JSONFilename())
-
-		if err := fs.appendToFile(fs.jsonFilename, fs.format.JSONSeparator()); err != nil {
-			return fmt.Errorf("error writing json separator > %w", err)
-		}
+	if err := fs.open(); err != nil {
+		return err
 	}
 
 	fs.initialized = true
+	fs.compressRotated()
 
 	if err := fs.rotate(); err != nil {
 		return fmt.Errorf("vloggo cleanup failed > %w", err)
@@ -179,116 +507,196 @@ func (fs *FileService) rotate() error {
 	return nil
 }
 
-// rotateTxt rotates .txt log files
-// Scans the log directory, sorts files by modification time (newest first),
-// and deletes the oldest files when count exceeds cfg.Filecount.Txt
+// rotateTxt rotates txt log files, compressed or not
+// Applies cfg.Filecount.Txt and cfg.Retention to the txt directory
 func (fs *FileService) rotateTxt() error {
+	if err := fs.retain(fs.cfg.Directory.Txt, filepath.Ext(fs.format.Filename()), fs.cfg.Filecount.Txt); err != nil {
+		return fmt.Errorf("error rotating txt files > %w", err)
+	}
+	return nil
+}
 
-	txtDir := fs.cfg.Directory.Txt
+// rotateJson rotates json log files, compressed or not
+// Applies cfg.Filecount.Json and cfg.Retention to the json directory
+func (fs *FileService) rotateJson() error {
+	if err := fs.retain(fs.cfg.Directory.Json, filepath.Ext(fs.format.JSONFilename()), fs.cfg.Filecount.Json); err != nil {
+		return fmt.Errorf("error rotating json files > %w", err)
+	}
+	return nil
+}
 
-	files, err := os.ReadDir(txtDir)
+// retain deletes the log files with extension ext in dir that fall outside the retention policies
+// Files are sorted newest first by the date and index in their name, falling back to the period of their mtime
+// count is a number of rotation periods, the size-rotated parts of a period count as one
+// A file is deleted when it is beyond count periods, when its period ended more than cfg.Retention.MaxAge ago,
+// or when it pushes the directory total past cfg.Retention.MaxTotalBytes. Zero values disable each policy
+// The active file, files being compressed or handled by rotation hooks, symlinks
+// and files of the current period this service has not released are never deleted
+// Must be called with fs.mu held
+func (fs *FileService) retain(dir, ext string, count int) error {
+	files, err := os.ReadDir(dir)
 	if err != nil {
-		return fmt.Errorf("error reading txt directory > %w", err)
+		return fmt.Errorf("error reading directory > %w", err)
 	}
 
 	type fileInfo struct {
 		path  string
-		mtime time.Time
+		date  time.Time
+		index int
+		size  int64
 	}
 
 	var logFiles []fileInfo
 	for _, file := range files {
-		if filepath.Ext(file.Name()) == ".txt" {
-			filePath := filepath.Join(txtDir, file.Name())
-			info, err := file.Info()
-			if err != nil {
-				continue
-			}
-			logFiles = append(logFiles, fileInfo{
-				path:  filePath,
-				mtime: info.ModTime(),
-			})
+		name := file.Name()
+		if file.Type()&os.ModeSymlink != 0 {
+			continue
+		}
+		if filepath.Ext(name) != ext && !strings.HasSuffix(name, ext+gzipExt) {
+			continue
 		}
+
+		info, err := file.Info()
+		if err != nil {
+			continue
+		}
+
+		date, index, ok := fs.format.ParseFilename(name)
+		if !ok {
+			date, index = fs.format.PeriodStart(info.ModTime()), 0
+		}
+
+		logFiles = append(logFiles, fileInfo{
+			path:  filepath.Join(dir, name),
+			date:  date,
+			index: index,
+			size:  info.Size(),
+		})
 	}
 
 	sort.Slice(logFiles, func(i, j int) bool {
-		return logFiles[i].mtime.After(logFiles[j].mtime)
+		if !logFiles[i].date.Equal(logFiles[j].date) {
+			return logFiles[i].date.After(logFiles[j].date)
+		}
+		return logFiles[i].index > logFiles[j].index
 	})
 
-	if len(logFiles) > fs.cfg.Filecount.Txt {
-		filesToDelete := logFiles[fs.cfg.Filecount.Txt:]
-		for _, file := range filesToDelete {
-			if err := os.Remove(file.path); err != nil {
-				fmt.Printf("[VLoggo] > [%s] [%s] [ERROR] : error deleting old txt file > %v\n",
-					fs.cfg.Client,
-					fs.format.Date(),
-					err,
-				)
-			}
+	now := time.Now()
+	var total int64
+	var periods int
+
+	for i, file := range logFiles {
+		total += file.size
+		if i == 0 || !file.date.Equal(logFiles[i-1].date) {
+			periods++
+		}
+
+		expired := (count > 0 && periods > count) ||
+			(fs.cfg.Retention.MaxAge > 0 && now.Sub(fs.format.NextPeriod(file.date)) > fs.cfg.Retention.MaxAge) ||
+			(fs.cfg.Retention.MaxTotalBytes > 0 && total > fs.cfg.Retention.MaxTotalBytes)
+
+		if !expired || fs.isActive(file.path) || fs.compressing[file.path] || fs.hooking[file.path] > 0 {
+			continue
+		}
+
+		if !strings.HasSuffix(file.path, gzipExt) && !fs.releasable(file.path, file.date) {
+			continue
+		}
+
+		if err := os.Remove(file.path); err != nil {
+			fmt.Printf("[VLoggo] > [%s] [%s] [ERROR] : error deleting old log file > %v\n",
+				fs.cfg.Client,
+				fs.format.Date(),
+				err,
+			)
 		}
 	}
 
 	return nil
 }
 
-// rotateJson rotates .jsonl log files
-// Scans the log directory, sorts files by modification time (newest first),
-// and deletes the oldest files when count exceeds cfg.Filecount.Json
-func (fs *FileService) rotateJson() error {
-	jsonDir := fs.cfg.Directory.Json
-
-	files, err := os.ReadDir(jsonDir)
-	if err != nil {
-		return fmt.Errorf("error reading json directory: %w", err)
-	}
+// WriteTxt writes a text line to the current txt log file
+// Automatically verifies if rotation is needed before writing
+// Rolls to the next indexed file when the line would make the current one exceed cfg.MaxSize
+// Lines are buffered and flushed right away when level is at or above cfg.Flush.Level
+func (fs *FileService) WriteTxt(level types.LogLevel, line string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
 
-	type fileInfo struct {
-		path  string
-		mtime time.Time
+	if err := fs.prepare(); err != nil {
+		return err
 	}
 
-	var logFiles []fileInfo
-	for _, file := range files {
-		if filepath.Ext(file.Name()) == ".jsonl" {
-			filePath := filepath.Join(jsonDir, file.Name())
-			info, err := file.Info()
-			if err != nil {
-				continue
-			}
-			logFiles = append(logFiles, fileInfo{
-				path:  filePath,
-				mtime: info.ModTime(),
-			})
+	if fs.txt.full(len(line), fs.cfg.MaxSize) {
+		if err := fs.openTxt(fs.txt.index + 1); err != nil {
+			return err
+		}
+		if err := fs.rotateTxt(); err != nil {
+			return fmt.Errorf("vloggo cleanup failed > %w", err)
 		}
 	}
 
-	sort.Slice(logFiles, func(i, j int) bool {
-		return logFiles[i].mtime.After(logFiles[j].mtime)
-	})
+	if err := fs.txt.write(line); err != nil {
+		return fmt.Errorf("error writing txt > %w", err)
+	}
 
-	if len(logFiles) > fs.cfg.Filecount.Json {
-		filesToDelete := logFiles[fs.cfg.Filecount.Json:]
-		for _, file := range filesToDelete {
-			if err := os.Remove(file.path); err != nil {
-				fmt.Printf("[VLoggo] > [%s] [%s] [ERROR] : error deleting old json file > %v\n",
-					fs.cfg.Client,
-					fs.format.Date(),
-					err,
-				)
-			}
+	if fs.cfg.Flush.Level != "" && level.Severity() >= fs.cfg.Flush.Level.Severity() {
+		if err := fs.txt.flush(); err != nil {
+			return fmt.Errorf("error flushing txt > %w", err)
 		}
 	}
 
 	return nil
 }
 
-// Write writes a log line to the current log file
-// Automatically verifies if rotation is needed before writing
-// If JSON is enabled and jsonLine is provided, writes to both txt and json files
-func (fs *FileService) Write(line string, jsonLine ...string) error {
+// JSON reports whether the service writes json log files, as set by cfg.Json when it was created
+func (fs *FileService) JSON() bool {
+	return fs.cfg.Json
+}
+
+// WriteJSON writes a JSON line to the current json log file
+// Behaves like WriteTxt, and returns an error if cfg.Json was disabled when the service was created
+func (fs *FileService) WriteJSON(level types.LogLevel, line string) error {
 	fs.mu.Lock()
 	defer fs.mu.Unlock()
 
+	if err := fs.prepare(); err != nil {
+		return err
+	}
+
+	if fs.json == nil {
+		return fmt.Errorf("json output not enabled")
+	}
+
+	if fs.json.full(len(line), fs.cfg.MaxSize) {
+		if err := fs.openJson(fs.json.index + 1); err != nil {
+			return err
+		}
+		if err := fs.rotateJson(); err != nil {
+			return fmt.Errorf("vloggo cleanup failed > %w", err)
+		}
+	}
+
+	if err := fs.json.write(line); err != nil {
+		return fmt.Errorf("error writing json > %w", err)
+	}
+
+	if fs.cfg.Flush.Level != "" && level.Severity() >= fs.cfg.Flush.Level.Severity() {
+		if err := fs.json.flush(); err != nil {
+			return fmt.Errorf("error flushing json > %w", err)
+		}
+	}
+
+	return nil
+}
+
+// prepare checks the service can be written to and rolls the files over if the period changed
+// Must be called with fs.mu held
+func (fs *FileService) prepare() error {
+	if fs.closed {
+		return fmt.Errorf("file service closed")
+	}
+
 	if !fs.initialized {
 		return fmt.Errorf("file service not initialized")
 	}
@@ -301,14 +709,57 @@ func (fs *FileService) Write(line string, jsonLine ...string) error {
 		)
 	}
 
-	if err := fs.appendToFile(fs.txtFilename, line); err != nil {
-		return fmt.Errorf("error writing txt > %w", err)
+	return nil
+}
+
+// Flush writes all buffered lines to the current log files
+func (fs *FileService) Flush() error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	return fs.flush()
+}
+
+// flush writes all buffered lines to the current log files
+// Must be called with fs.mu held
+func (fs *FileService) flush() error {
+	if err := fs.txt.flush(); err != nil {
+		return fmt.Errorf("error flushing txt > %w", err)
 	}
 
-	if fs.cfg.Json && len(jsonLine) > 0 {
-		if err := fs.appendToFile(fs.jsonFilename, jsonLine[0]); err != nil {
-			return fmt.Errorf("error writing json > %w", err)
-		}
+	if err := fs.json.flush(); err != nil {
+		return fmt.Errorf("error flushing json > %w", err)
+	}
+
+	return nil
+}
+
+// Close flushes and closes the current log files and stops the background flush
+// Waits for background compressions to finish
+// Later writes return an error. Calling Close more than once has no effect
+func (fs *FileService) Close() error {
+	fs.mu.Lock()
+
+	if fs.closed {
+		fs.mu.Unlock()
+		return nil
+	}
+
+	fs.closed = true
+	close(fs.done)
+
+	txtErr := fs.txt.close()
+	jsonErr := fs.json.close()
+	fs.txt, fs.json = nil, nil
+
+	fs.mu.Unlock()
+	fs.wg.Wait()
+
+	if txtErr != nil {
+		return fmt.Errorf("error closing txt > %w", txtErr)
+	}
+	if jsonErr != nil {
+		return fmt.Errorf("error closing json > %w", jsonErr)
 	}
 
 	return nil
@@ -0,0 +1,490 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	types "github.com/vinialx/vloggo-go/types"
+)
+
+// newTestFileService creates a FileService writing to temporary directories
+// edit, when set, changes the config before the service is created
+func newTestFileService(t testing.TB, edit func(cfg *types.VLoggoConfig)) *FileService {
+	t.Helper()
+
+	dir := t.TempDir()
+	cfg := types.VLoggoConfig{
+		Client: "test",
+		Directory: types.Paths{
+			Txt:  dir,
+			Json: filepath.Join(dir, "json"),
+		},
+	}
+	if edit != nil {
+		edit(&cfg)
+	}
+
+	fs := NewFileService(cfg)
+	t.Cleanup(func() { fs.Close() })
+
+	return fs
+}
+
+// onDisk returns the content of the current txt file as written to disk
+func onDisk(t testing.TB, fs *FileService) string {
+	t.Helper()
+
+	content, err := os.ReadFile(filepath.Join(fs.cfg.Directory.Txt, fs.format.Filename()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(content)
+}
+
+func TestFlushSize(t *testing.T) {
+	fs := newTestFileService(t, func(cfg *types.VLoggoConfig) {
+		cfg.Flush.Size = 256
+	})
+
+	if err := fs.WriteTxt(types.Info, "first line\n"); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(onDisk(t, fs), "first line") {
+		t.Fatal("line written before the buffer was full")
+	}
+
+	for i := 0; i < 30; i++ {
+		if err := fs.WriteTxt(types.Info, "filling the buffer\n"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !strings.Contains(onDisk(t, fs), "first line") {
+		t.Fatal("line not written once the buffer was full")
+	}
+}
+
+func TestFlushLevel(t *testing.T) {
+	fs := newTestFileService(t, func(cfg *types.VLoggoConfig) {
+		cfg.Flush.Level = types.Error
+	})
+
+	if err := fs.WriteTxt(types.Warn, "warn line\n"); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(onDisk(t, fs), "warn line") {
+		t.Fatal("WARN line flushed with Flush.Level ERROR")
+	}
+
+	if err := fs.WriteTxt(types.Error, "error line\n"); err != nil {
+		t.Fatal(err)
+	}
+	content := onDisk(t, fs)
+	if !strings.Contains(content, "warn line") || !strings.Contains(content, "error line") {
+		t.Fatalf("ERROR line did not flush the buffer: %q", content)
+	}
+}
+
+func TestFlushInterval(t *testing.T) {
+	fs := newTestFileService(t, func(cfg *types.VLoggoConfig) {
+		cfg.Flush.Interval = 20 * time.Millisecond
+	})
+
+	if err := fs.WriteTxt(types.Info, "timed line\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !strings.Contains(onDisk(t, fs), "timed line") {
+		if time.Now().After(deadline) {
+			t.Fatal("line not flushed by the background flush")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestCloseFlushes(t *testing.T) {
+	fs := newTestFileService(t, nil)
+
+	if err := fs.WriteTxt(types.Info, "last line\n"); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(onDisk(t, fs), "last line") {
+		t.Fatal("line flushed before Close")
+	}
+
+	if err := fs.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(onDisk(t, fs), "last line") {
+		t.Fatal("Close did not flush the buffer")
+	}
+
+	if err := fs.WriteTxt(types.Info, "too late\n"); err == nil {
+		t.Fatal("write after Close succeeded")
+	}
+}
+
+func TestWriteKeepsLinesWhole(t *testing.T) {
+	fs := newTestFileService(t, func(cfg *types.VLoggoConfig) {
+		cfg.Flush.Size = 64
+	})
+	fs.Flush()
+
+	lines := []string{
+		"short line\n",
+		"a line long enough to overflow what is left of the small buffer\n",
+		"another line that does not fit in what is left\n",
+		strings.Repeat("x", 100) + " line larger than the whole buffer\n",
+		"tail\n",
+	}
+
+	for _, line := range lines {
+		if err := fs.WriteTxt(types.Info, line); err != nil {
+			t.Fatal(err)
+		}
+		if content := onDisk(t, fs); !strings.HasSuffix(content, "\n") {
+			t.Fatalf("line split across writes, file ends with %q", content[max(len(content)-20, 0):])
+		}
+	}
+
+	fs.Flush()
+	if content := onDisk(t, fs); !strings.HasSuffix(content, strings.Join(lines, "")) {
+		t.Fatalf("lines out of order or missing: %q", content)
+	}
+}
+
+// current returns the target of the current symlink in dir
+func current(t testing.TB, dir, ext string) string {
+	t.Helper()
+
+	target, err := os.Readlink(filepath.Join(dir, "current"+ext))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return target
+}
+
+func TestTemplates(t *testing.T) {
+	fs := newTestFileService(t, func(cfg *types.VLoggoConfig) {
+		cfg.Client = "api"
+		cfg.Json = true
+		cfg.MaxSize = 256
+		cfg.Templates = types.Templates{
+			Txt:     "{client}-{date}-{pid}.log",
+			Json:    "{client}-{date}.jsonl",
+			Current: true,
+		}
+	})
+
+	name := "api-" + fs.format.Period(time.Now()) + "-" + strconv.Itoa(os.Getpid())
+	if want := filepath.Join(fs.cfg.Directory.Txt, name+".log"); fs.txt.path != want {
+		t.Fatalf("txt file %s, want %s", fs.txt.path, want)
+	}
+	if target := current(t, fs.cfg.Directory.Txt, ".log"); target != name+".log" {
+		t.Errorf("current.log points at %s, want %s", target, name+".log")
+	}
+	if target := current(t, fs.cfg.Directory.Json, ".jsonl"); target != "api-"+fs.format.Period(time.Now())+".jsonl" {
+		t.Errorf("current.jsonl points at %s", target)
+	}
+	if _, err := os.Lstat(filepath.Join(fs.cfg.Directory.Txt, "current.txt")); err == nil {
+		t.Error("current.txt created for a .log template")
+	}
+
+	for i := 0; i < 10; i++ {
+		if err := fs.WriteTxt(types.Info, strings.Repeat("x", 63)+"\n"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if fs.txt.index == 0 {
+		t.Fatal("txt file not rotated past MaxSize")
+	}
+	if target := current(t, fs.cfg.Directory.Txt, ".log"); target != filepath.Base(fs.txt.path) {
+		t.Errorf("current.log points at %s after rotation, want %s", target, filepath.Base(fs.txt.path))
+	}
+}
+
+// oldLog creates a log file of size bytes named after the period days ago, with a size-rotation index when index > 0
+func oldLog(t testing.TB, fs *FileService, days, index, size int) string {
+	t.Helper()
+
+	name := "log-" + fs.format.Period(time.Now().AddDate(0, 0, -days))
+	if index > 0 {
+		name += "." + strconv.Itoa(index)
+	}
+	path := filepath.Join(fs.cfg.Directory.Txt, name+".txt")
+
+	if err := os.WriteFile(path, []byte(strings.Repeat("x", size)), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+// retainTxt applies the retention policies to the txt directory
+func retainTxt(t testing.TB, fs *FileService) {
+	t.Helper()
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if err := fs.rotateTxt(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// exists reports whether path is still on disk
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func TestRetainCountsPeriods(t *testing.T) {
+	fs := newTestFileService(t, func(cfg *types.VLoggoConfig) {
+		cfg.Filecount.Txt = 3
+	})
+
+	busy := []string{oldLog(t, fs, 1, 0, 10), oldLog(t, fs, 1, 1, 10), oldLog(t, fs, 1, 2, 10)}
+	kept := oldLog(t, fs, 2, 0, 10)
+	expired := oldLog(t, fs, 3, 0, 10)
+
+	retainTxt(t, fs)
+
+	for _, path := range append(busy, kept) {
+		if !exists(path) {
+			t.Errorf("%s deleted, its period is one of the last 3", filepath.Base(path))
+		}
+	}
+	if exists(expired) {
+		t.Errorf("%s kept, its period is beyond the last 3", filepath.Base(expired))
+	}
+}
+
+func TestRetainMaxAge(t *testing.T) {
+	fs := newTestFileService(t, func(cfg *types.VLoggoConfig) {
+		cfg.Retention.MaxAge = 24 * time.Hour
+	})
+
+	recent := oldLog(t, fs, 1, 0, 10)
+	expired := oldLog(t, fs, 3, 0, 10)
+
+	retainTxt(t, fs)
+
+	if !exists(recent) {
+		t.Error("yesterday's file deleted, its period ended less than MaxAge ago")
+	}
+	if exists(expired) {
+		t.Error("file from 3 days ago kept, its period ended more than MaxAge ago")
+	}
+	if !exists(fs.txt.path) {
+		t.Error("active file deleted")
+	}
+}
+
+func TestRetainMaxTotalBytes(t *testing.T) {
+	fs := newTestFileService(t, func(cfg *types.VLoggoConfig) {
+		cfg.Retention.MaxTotalBytes = 250
+	})
+
+	newest := oldLog(t, fs, 1, 0, 100)
+	older := oldLog(t, fs, 2, 0, 100)
+	oldest := oldLog(t, fs, 3, 0, 100)
+
+	retainTxt(t, fs)
+
+	if !exists(newest) || !exists(older) {
+		t.Error("newest files deleted although they fit in MaxTotalBytes")
+	}
+	if exists(oldest) {
+		t.Error("oldest file kept although it pushes the directory past MaxTotalBytes")
+	}
+}
+
+// stdout returns what fn printed on the console
+func stdout(t testing.TB, fn func()) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		out <- string(data)
+	}()
+
+	previous := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = previous }()
+
+	fn()
+
+	w.Close()
+	return <-out
+}
+
+// rollover writes numbered lines until the txt file rolls over to the next index
+// Returns the last line written to the previous file
+func rollover(t testing.TB, fs *FileService) string {
+	t.Helper()
+
+	last := ""
+	for i := 0; ; i++ {
+		line := fmt.Sprintf("line %d\n", i)
+		if err := fs.WriteTxt(types.Info, line); err != nil {
+			t.Fatal(err)
+		}
+
+		fs.mu.Lock()
+		index := fs.txt.index
+		fs.mu.Unlock()
+
+		if index > 0 {
+			return last
+		}
+		last = line
+	}
+}
+
+func TestRotateHooks(t *testing.T) {
+	fs := newTestFileService(t, func(cfg *types.VLoggoConfig) {
+		cfg.MaxSize = 200
+		cfg.Compress = true
+	})
+
+	release := make(chan struct{})
+	called := make(chan string, 1)
+
+	fs.OnRotate(func(oldPath, newPath string) error {
+		content, err := os.ReadFile(oldPath)
+		if err != nil {
+			return err
+		}
+		called <- string(content)
+		<-release
+		return nil
+	})
+	fs.OnRotate(func(oldPath, newPath string) error {
+		return errors.New("upload refused")
+	})
+	fs.OnRotate(func(oldPath, newPath string) error {
+		panic("hook bug")
+	})
+
+	oldPath := filepath.Join(fs.cfg.Directory.Txt, fs.format.Filename())
+	last := rollover(t, fs)
+
+	if content := <-called; !strings.HasSuffix(content, last) {
+		t.Fatalf("hook ran before the old file was flushed, it ends with %q, want %q", content[max(len(content)-20, 0):], last)
+	}
+
+	if !exists(oldPath) || exists(oldPath+gzipExt) {
+		t.Fatal("old file compressed while a hook was still running")
+	}
+
+	output := stdout(t, func() {
+		close(release)
+		if err := fs.Close(); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	if !strings.Contains(output, "rotation hook failed for "+oldPath+" > upload refused") {
+		t.Errorf("hook error not reported: %q", output)
+	}
+	if !strings.Contains(output, "rotation hook panicked > hook bug") {
+		t.Errorf("hook panic not reported: %q", output)
+	}
+	if exists(oldPath) || !exists(oldPath+gzipExt) {
+		t.Error("old file not compressed once the hooks returned")
+	}
+}
+
+func TestRotateHookDelaysRetention(t *testing.T) {
+	fs := newTestFileService(t, func(cfg *types.VLoggoConfig) {
+		cfg.MaxSize = 200
+		cfg.Retention.MaxTotalBytes = 1
+	})
+
+	release := make(chan struct{})
+	called := make(chan struct{})
+	fs.OnRotate(func(oldPath, newPath string) error {
+		close(called)
+		<-release
+		return nil
+	})
+
+	oldPath := filepath.Join(fs.cfg.Directory.Txt, fs.format.Filename())
+	rollover(t, fs)
+	<-called
+
+	retainTxt(t, fs)
+	if !exists(oldPath) {
+		t.Fatal("old file deleted while a hook was still running")
+	}
+
+	close(release)
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(10 * time.Millisecond) {
+		fs.mu.Lock()
+		hooking := fs.hooking[oldPath]
+		fs.mu.Unlock()
+
+		if hooking == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("hook did not return")
+		}
+	}
+
+	retainTxt(t, fs)
+	if exists(oldPath) {
+		t.Error("old file kept past MaxTotalBytes once the hook returned")
+	}
+}
+
+const benchLine = "[test] [16/10/2026 10:00:00] [INFO] [BENCH] [file_test.go:1] : benchmark line\n"
+
+// BenchmarkWriteTxt measures buffered writes to the file kept open by FileService
+func BenchmarkWriteTxt(b *testing.B) {
+	fs := newTestFileService(b, nil)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := fs.WriteTxt(types.Info, benchLine); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkWriteTxtReopen measures the previous behavior of opening, writing and closing the file for every line
+func BenchmarkWriteTxtReopen(b *testing.B) {
+	path := filepath.Join(b.TempDir(), "log.txt")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := f.WriteString(benchLine); err != nil {
+			b.Fatal(err)
+		}
+		if err := f.Close(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
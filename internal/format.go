@@ -1,22 +1,54 @@
-// Package services provides formatting and file management services for VLoggo.
-// Includes FormatService for log formatting and timestamps, and FileService for file operations,
-// log rotation and retention management.
+// Package services provides the services and sinks behind VLoggo.
+// Includes FormatService for log formatting and timestamps, FileService for log files, rotation
+// and retention, EmailService for notifications, AsyncService for the asynchronous pipeline,
+// and the console, file, writer, syslog, journald, webhook, Loki and Elasticsearch sinks.
 package services
 
 import (
 	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	types "github.com/vinialx/vloggo-go/types"
 )
 
+// ANSI escape codes used by ColorLine
+const (
+	ansiReset   = "\033[0m"
+	ansiBold    = "\033[1m"
+	ansiDim     = "\033[2m"
+	ansiRed     = "\033[31m"
+	ansiGreen   = "\033[32m"
+	ansiYellow  = "\033[33m"
+	ansiMagenta = "\033[35m"
+)
+
+// levelColors maps each log level to the color of its level tag
+var levelColors = map[types.LogLevel]string{
+	types.Info:  ansiGreen,
+	types.Warn:  ansiYellow,
+	types.Error: ansiRed,
+	types.Fatal: ansiBold + ansiRed,
+	types.Debug: ansiMagenta,
+}
+
 // FormatService manages log entry formatting, filenames and timestamps
+// Location sets the timezone used for filename dates, defaulting to the local timezone
+// Interval sets the rotation period used for filenames, defaulting to daily, rounded to whole seconds
+// TxtTemplate and JSONTemplate set the filename templates, defaulting to DefaultTemplate and DefaultJSONTemplate
 type FormatService struct {
-	Client string
+	Client       string
+	Location     *time.Location
+	Interval     time.Duration
+	TxtTemplate  string
+	JSONTemplate string
 }
 
 // NewFormatService creates a new FormatService instance
@@ -31,52 +63,371 @@ func NewFormatService(client string) *FormatService {
 }
 
 // Date formats a timestamp in Brazilian format (DD/MM/YYYY HH:MM:SS)
-// If no time is provided, uses current time
+// If no time (or a zero time) is provided, uses current time
 func (fs *FormatService) Date(t ...time.Time) string {
 	date := time.Now()
-	if len(t) > 0 {
+	if len(t) > 0 && !t[0].IsZero() {
 		date = t[0]
 	}
 	return date.Format("02/01/2006 15:04:05")
 }
 
 // IsoDate formats a timestamp in ISO 8601 / RFC3339 format (UTC)
-// If no time is provided, uses current time
+// If no time (or a zero time) is provided, uses current time
 func (fs *FormatService) IsoDate(t ...time.Time) string {
 	date := time.Now()
-	if len(t) > 0 {
+	if len(t) > 0 && !t[0].IsZero() {
 		date = t[0]
 	}
 	return date.UTC().Format(time.RFC3339)
 }
 
-// Filename generates the log filename based on current date
-// Format: log-YYYY-MM-DD.txt
-func (fs *FormatService) Filename() string {
-	now := time.Now()
-	dateStr := now.Format("2006-01-02")
-	return "log-" + dateStr + ".txt"
+// Now returns the current time in the configured Location
+func (fs *FormatService) Now() time.Time {
+	if fs.Location != nil {
+		return time.Now().In(fs.Location)
+	}
+	return time.Now()
+}
+
+// epochMonday is the reference used to align multi-day periods, so weekly periods start on Monday
+var epochMonday = time.Date(1970, time.January, 5, 0, 0, 0, 0, time.UTC)
+
+// interval returns the rotation interval, rounded to whole seconds with a minimum of one second
+// A zero Interval means daily periods
+func (fs *FormatService) interval() time.Duration {
+	if fs.Interval <= 0 {
+		return types.Daily
+	}
+
+	return max(fs.Interval.Round(time.Second), time.Second)
+}
+
+// PeriodStart returns the start of the rotation period containing t, in Location
+// Periods shorter than a day are aligned to local midnight, whole days to Monday 1970-01-05,
+// and other intervals longer than a day to Monday 1970-01-05 00:00 in Location
+func (fs *FormatService) PeriodStart(t time.Time) time.Time {
+	if fs.Location != nil {
+		t = t.In(fs.Location)
+	}
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+
+	interval := fs.interval()
+
+	switch {
+	case interval < types.Daily:
+		return midnight.Add(t.Sub(midnight) / interval * interval)
+
+	case interval%types.Daily == 0:
+		days := int(interval / types.Daily)
+		date := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		offset := int(date.Sub(epochMonday)/types.Daily) % days
+		if offset < 0 {
+			offset += days
+		}
+		return midnight.AddDate(0, 0, -offset)
+
+	default:
+		epoch := time.Date(1970, time.January, 5, 0, 0, 0, 0, t.Location())
+		elapsed := t.Sub(epoch)
+		periods := elapsed / interval
+		if elapsed < 0 && elapsed%interval != 0 {
+			periods--
+		}
+		return epoch.Add(periods * interval)
+	}
+}
+
+// Period returns the key of the rotation period containing t, as used in filenames
+// Format: YYYY-MM-DD for whole days, YYYY-MM-DDTHH for whole hours, YYYY-MM-DDTHHMM for whole minutes,
+// YYYY-MM-DDTHHMMSS otherwise
+// Files are rolled over whenever this key changes
+func (fs *FormatService) Period(t time.Time) string {
+	start := fs.PeriodStart(t)
+	interval := fs.interval()
+
+	switch {
+	case interval%types.Daily == 0:
+		return start.Format("2006-01-02")
+	case interval%time.Hour == 0:
+		return start.Format("2006-01-02T15")
+	case interval%time.Minute == 0:
+		return start.Format("2006-01-02T1504")
+	default:
+		return start.Format("2006-01-02T150405")
+	}
+}
+
+// NextPeriod returns the start of the rotation period following t
+// Periods shorter than a day never cross midnight
+func (fs *FormatService) NextPeriod(t time.Time) time.Time {
+	start := fs.PeriodStart(t)
+	interval := fs.interval()
+
+	switch {
+	case interval%types.Daily == 0:
+		return start.AddDate(0, 0, int(interval/types.Daily))
+
+	case interval < types.Daily:
+		next := start.Add(interval)
+		midnight := time.Date(start.Year(), start.Month(), start.Day()+1, 0, 0, 0, 0, start.Location())
+		if next.After(midnight) {
+			return midnight
+		}
+		return next
+
+	default:
+		return start.Add(interval)
+	}
+}
+
+// Default filename templates, used when TxtTemplate or JSONTemplate are empty
+const (
+	DefaultTemplate     = "log-{date}.txt"
+	DefaultJSONTemplate = "log-{date}.jsonl"
+)
+
+// txtTemplate returns the txt filename template
+func (fs *FormatService) txtTemplate() string {
+	if fs.TxtTemplate == "" {
+		return DefaultTemplate
+	}
+	return fs.TxtTemplate
+}
+
+// jsonTemplate returns the json filename template
+func (fs *FormatService) jsonTemplate() string {
+	if fs.JSONTemplate == "" {
+		return DefaultJSONTemplate
+	}
+	return fs.JSONTemplate
+}
+
+// render fills a filename template for the current rotation period
+// Placeholders: {client}, {date} (the period key), {pid} and {index}
+// {index} renders as ".N" for a size-rotation index N > 0 and as nothing otherwise;
+// when missing from the template, it is inserted before the extension
+func (fs *FormatService) render(template string, index ...int) string {
+	suffix := ""
+	if len(index) > 0 && index[0] > 0 {
+		suffix = "." + strconv.Itoa(index[0])
+	}
+
+	if !strings.Contains(template, "{index}") {
+		ext := filepath.Ext(template)
+		template = strings.TrimSuffix(template, ext) + "{index}" + ext
+	}
+
+	return strings.NewReplacer(
+		"{client}", fs.Client,
+		"{date}", fs.Period(fs.Now()),
+		"{pid}", strconv.Itoa(os.Getpid()),
+		"{index}", suffix,
+	).Replace(template)
+}
+
+// Filename generates the log filename from TxtTemplate for the current rotation period
+// Default format: log-PERIOD.txt, or log-PERIOD.N.txt when a size-rotation index N > 0 is provided
+func (fs *FormatService) Filename(index ...int) string {
+	return fs.render(fs.txtTemplate(), index...)
 }
 
-// JSONFilename generates the JSON log filename based on current date
-// Format: log-YYYY-MM-DD.jsonl
-func (fs *FormatService) JSONFilename() string {
-	now := time.Now()
-	dateStr := now.Format("2006-01-02")
-	return fmt.Sprintf("log-%s.jsonl", dateStr)
+// JSONFilename generates the JSON log filename from JSONTemplate for the current rotation period
+// Default format: log-PERIOD.jsonl, or log-PERIOD.N.jsonl when a size-rotation index N > 0 is provided
+func (fs *FormatService) JSONFilename(index ...int) string {
+	return fs.render(fs.jsonTemplate(), index...)
+}
+
+// Fields converts variadic log arguments into structured fields
+// Accepts types.Field values and alternating key-value pairs
+// A key without a value, or a non-string key, is stored under "!BADKEY"
+func Fields(args ...any) types.Fields {
+	if len(args) == 0 {
+		return nil
+	}
+
+	fields := make(types.Fields, 0, len(args)/2+1)
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i].(type) {
+		case types.Field:
+			fields = append(fields, arg)
+		case types.Fields:
+			fields = append(fields, arg...)
+		case string:
+			if i+1 >= len(args) {
+				fields = append(fields, types.Field{Key: "!BADKEY", Value: arg})
+				continue
+			}
+			fields = append(fields, types.Field{Key: arg, Value: args[i+1]})
+			i++
+		default:
+			fields = append(fields, types.Field{Key: "!BADKEY", Value: arg})
+		}
+	}
+
+	return fields
+}
+
+// FieldsText renders structured fields as space separated k=v pairs
+// Values containing spaces, quotes or '=' are quoted
+func (fs *FormatService) FieldsText(fields types.Fields) string {
+	var b strings.Builder
+
+	for _, field := range fields {
+		value := field.Value
+		if e, ok := value.(error); ok {
+			value = e.Error()
+		}
+
+		text := fmt.Sprint(value)
+		if text == "" || strings.ContainsAny(text, " \t\r\n\"=") {
+			text = strconv.Quote(text)
+		}
+
+		b.WriteString(" " + field.Key + "=" + text)
+	}
+
+	return b.String()
+}
+
+// periodLayouts maps the length of a period key to its time layout
+var periodLayouts = map[int]string{
+	len("2006-01-02"):        "2006-01-02",
+	len("2006-01-02T15"):     "2006-01-02T15",
+	len("2006-01-02T1504"):   "2006-01-02T1504",
+	len("2006-01-02T150405"): "2006-01-02T150405",
+}
+
+// patterns caches the regular expressions built by pattern, keyed by client and template
+var patterns sync.Map
+
+// pattern builds a regular expression matching the names produced by a filename template,
+// compressed or not, capturing the period key and the size-rotation index
+func (fs *FormatService) pattern(template string) *regexp.Regexp {
+	key := fs.Client + "\x00" + template
+	if re, ok := patterns.Load(key); ok {
+		return re.(*regexp.Regexp)
+	}
+
+	if !strings.Contains(template, "{index}") {
+		ext := filepath.Ext(template)
+		template = strings.TrimSuffix(template, ext) + "{index}" + ext
+	}
+
+	var b strings.Builder
+	b.WriteString("^")
+
+	for len(template) > 0 {
+		start := strings.Index(template, "{")
+		end := strings.Index(template, "}")
+		if start < 0 || end < start {
+			b.WriteString(regexp.QuoteMeta(template))
+			break
+		}
+
+		b.WriteString(regexp.QuoteMeta(template[:start]))
+
+		switch template[start : end+1] {
+		case "{client}":
+			b.WriteString(regexp.QuoteMeta(fs.Client))
+		case "{date}":
+			b.WriteString(`(?P<date>\d{4}-\d{2}-\d{2}(?:T\d{2}(?:\d{2}(?:\d{2})?)?)?)`)
+		case "{pid}":
+			b.WriteString(`\d+`)
+		case "{index}":
+			b.WriteString(`(?:\.(?P<index>\d+))?`)
+		default:
+			b.WriteString(regexp.QuoteMeta(template[start : end+1]))
+		}
+
+		template = template[end+1:]
+	}
+
+	b.WriteString(`(?:\.gz)?$`)
+
+	re := regexp.MustCompile(b.String())
+	patterns.Store(key, re)
+
+	return re
+}
+
+// ParseFilename extracts the period start and size-rotation index from a log filename
+// Accepts names produced by Filename and JSONFilename, compressed or not
+// The date is interpreted in Location
+// Returns ok false for names that do not match either template or whose template has no {date}
+func (fs *FormatService) ParseFilename(name string) (date time.Time, index int, ok bool) {
+	location := fs.Location
+	if location == nil {
+		location = time.Local
+	}
+
+	for _, template := range []string{fs.txtTemplate(), fs.jsonTemplate()} {
+		re := fs.pattern(template)
+
+		match := re.FindStringSubmatch(name)
+		if match == nil {
+			continue
+		}
+
+		if re.SubexpIndex("date") < 0 {
+			return time.Time{}, 0, false
+		}
+		dateStr := match[re.SubexpIndex("date")]
+
+		date, err := time.ParseInLocation(periodLayouts[len(dateStr)], dateStr, location)
+		if err != nil {
+			return time.Time{}, 0, false
+		}
+
+		if indexStr := match[re.SubexpIndex("index")]; indexStr != "" {
+			index, err = strconv.Atoi(indexStr)
+			if err != nil {
+				return time.Time{}, 0, false
+			}
+		}
+
+		return date, index, true
+	}
+
+	return time.Time{}, 0, false
 }
 
 // Line formats a log entry into a human-readable text line
-// Format: [Client] [Timestamp] [Level] [Code] [Caller] : Message
+// Format: [Client] [Timestamp] [Level] [Code] [Caller] : Message k=v
 func (fs *FormatService) Line(entry types.LogEntry) string {
-	timestamp := fs.Date()
-	return fmt.Sprintf("[%s] [%s] [%s] [%s] [%s] : %s\n",
+	timestamp := fs.Date(entry.Time)
+	return fmt.Sprintf("[%s] [%s] [%s] [%s] [%s] : %s%s\n",
 		fs.Client,
 		timestamp,
 		entry.Level,
 		entry.Code,
 		entry.Caller,
 		entry.Message,
+		fs.FieldsText(entry.Fields),
+	)
+}
+
+// ColorLine formats a log entry like Line, adding ANSI colors for terminal output
+// The level tag is colored by level, timestamp and caller are dimmed and FATAL entries are bold
+// Must never be used for file output
+func (fs *FormatService) ColorLine(entry types.LogEntry) string {
+	timestamp := fs.Date(entry.Time)
+
+	color := levelColors[entry.Level]
+
+	message := entry.Message
+	if entry.Level == types.Fatal {
+		message = ansiBold + message + ansiReset
+	}
+
+	return fmt.Sprintf("[%s] %s[%s]%s %s[%s]%s [%s] %s[%s]%s : %s%s\n",
+		fs.Client,
+		ansiDim, timestamp, ansiReset,
+		color, entry.Level, ansiReset,
+		entry.Code,
+		ansiDim, entry.Caller, ansiReset,
+		message,
+		fs.FieldsText(entry.Fields),
 	)
 }
 
@@ -90,7 +441,7 @@ func (fs *FormatService) JSONLine(entry types.LogEntry) string {
 		types.LogEntry
 	}{
 		Client:    fs.Client,
-		Timestamp: fs.IsoDate(),
+		Timestamp: fs.IsoDate(entry.Time),
 		LogEntry:  entry,
 	}
 	jsonBytes, err := json.Marshal(jsonEntry)
@@ -144,7 +495,7 @@ func (fs *FormatService) JSONSeparator() string {
 
 // Caller gets caller information (filename and line number) from the call stack
 // Used to identify where a log entry originated in the code
-// skip defines how many stack frames to skip (typically 3 for log methods)
+// skip defines how many stack frames to skip (typically 2 for log methods)
 // Returns "(unknown:0)" if information is unavailable
 func Caller(skip int) string {
 	_, file, line, ok := runtime.Caller(skip + 1)
@@ -154,4 +505,20 @@ func Caller(skip int) string {
 	parts := strings.Split(file, "/")
 	filename := parts[len(parts)-1]
 	return fmt.Sprintf("%s:%s", filename, strconv.Itoa(line))
+}
+
+// CallerFromPC gets caller information (filename and line number) from a program counter
+// Used when the call site was captured elsewhere, like in a slog.Record
+// Returns "(unknown:0)" if pc is zero or cannot be resolved
+func CallerFromPC(pc uintptr) string {
+	if pc == 0 {
+		return "(unknown:0)"
+	}
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	if frame.File == "" {
+		return "(unknown:0)"
+	}
+	parts := strings.Split(frame.File, "/")
+	filename := parts[len(parts)-1]
+	return fmt.Sprintf("%s:%s", filename, strconv.Itoa(frame.Line))
 }
\ No newline at end of file
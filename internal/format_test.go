@@ -0,0 +1,271 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	types "github.com/vinialx/vloggo-go/types"
+)
+
+func TestPeriod(t *testing.T) {
+	brt := time.FixedZone("BRT", -3*60*60)
+	at := time.Date(2026, time.October, 16, 15, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		interval time.Duration
+		location *time.Location
+		t        time.Time
+		period   string
+		start    time.Time
+		next     time.Time
+	}{
+		{
+			name:   "default is daily",
+			t:      at,
+			period: "2026-10-16",
+			start:  time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC),
+			next:   time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "hourly",
+			interval: types.Hourly,
+			t:        at,
+			period:   "2026-10-16T15",
+			start:    time.Date(2026, 10, 16, 15, 0, 0, 0, time.UTC),
+			next:     time.Date(2026, 10, 16, 16, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "weekly starts on monday",
+			interval: types.Weekly,
+			t:        at,
+			period:   "2026-10-12",
+			start:    time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC),
+			next:     time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "15 minutes",
+			interval: 15 * time.Minute,
+			t:        at,
+			period:   "2026-10-16T1500",
+			start:    time.Date(2026, 10, 16, 15, 0, 0, 0, time.UTC),
+			next:     time.Date(2026, 10, 16, 15, 15, 0, 0, time.UTC),
+		},
+		{
+			name:     "7 hours never crosses midnight",
+			interval: 7 * time.Hour,
+			t:        time.Date(2026, 10, 16, 22, 0, 0, 0, time.UTC),
+			period:   "2026-10-16T21",
+			start:    time.Date(2026, 10, 16, 21, 0, 0, 0, time.UTC),
+			next:     time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "30 seconds",
+			interval: 30 * time.Second,
+			t:        at,
+			period:   "2026-10-16T150400",
+			start:    time.Date(2026, 10, 16, 15, 4, 0, 0, time.UTC),
+			next:     time.Date(2026, 10, 16, 15, 4, 30, 0, time.UTC),
+		},
+		{
+			name:     "90 seconds",
+			interval: 90 * time.Second,
+			t:        at,
+			period:   "2026-10-16T150300",
+			start:    time.Date(2026, 10, 16, 15, 3, 0, 0, time.UTC),
+			next:     time.Date(2026, 10, 16, 15, 4, 30, 0, time.UTC),
+		},
+		{
+			name:     "sub-second rounds to one second",
+			interval: 300 * time.Millisecond,
+			t:        at.Add(500 * time.Millisecond),
+			period:   "2026-10-16T150405",
+			start:    time.Date(2026, 10, 16, 15, 4, 5, 0, time.UTC),
+			next:     time.Date(2026, 10, 16, 15, 4, 6, 0, time.UTC),
+		},
+		{
+			name:     "36 hours",
+			interval: 36 * time.Hour,
+			t:        at,
+			period:   "2026-10-15T12",
+			start:    time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC),
+			next:     time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "daily in another timezone",
+			location: brt,
+			t:        time.Date(2026, 10, 17, 1, 30, 0, 0, time.UTC),
+			period:   "2026-10-16",
+			start:    time.Date(2026, 10, 16, 0, 0, 0, 0, brt),
+			next:     time.Date(2026, 10, 17, 0, 0, 0, 0, brt),
+		},
+		{
+			name:     "hourly in another timezone",
+			interval: types.Hourly,
+			location: brt,
+			t:        time.Date(2026, 10, 17, 1, 30, 0, 0, time.UTC),
+			period:   "2026-10-16T22",
+			start:    time.Date(2026, 10, 16, 22, 0, 0, 0, brt),
+			next:     time.Date(2026, 10, 16, 23, 0, 0, 0, brt),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := NewFormatService("test")
+			fs.Interval = tt.interval
+			fs.Location = tt.location
+			if fs.Location == nil {
+				fs.Location = time.UTC
+			}
+
+			if got := fs.Period(tt.t); got != tt.period {
+				t.Errorf("Period = %s, want %s", got, tt.period)
+			}
+			if got := fs.PeriodStart(tt.t); !got.Equal(tt.start) {
+				t.Errorf("PeriodStart = %s, want %s", got, tt.start)
+			}
+			if got := fs.NextPeriod(tt.t); !got.Equal(tt.next) {
+				t.Errorf("NextPeriod = %s, want %s", got, tt.next)
+			}
+			if got := fs.Period(fs.NextPeriod(tt.t)); got == tt.period {
+				t.Errorf("Period does not change at NextPeriod, both %s", got)
+			}
+		})
+	}
+}
+
+func TestParseFilename(t *testing.T) {
+	brt := time.FixedZone("BRT", -3*60*60)
+	at := time.Date(2026, time.October, 16, 15, 4, 5, 0, brt)
+
+	intervals := []time.Duration{types.Daily, types.Hourly, types.Weekly, 36 * time.Hour, 15 * time.Minute, 90 * time.Second}
+
+	for _, interval := range intervals {
+		fs := NewFormatService("test")
+		fs.Interval = interval
+		fs.Location = brt
+
+		period := fs.Period(at)
+		start := fs.PeriodStart(at)
+
+		names := map[string]int{
+			"log-" + period + ".txt":        0,
+			"log-" + period + ".2.txt":      2,
+			"log-" + period + ".3.txt.gz":   3,
+			"log-" + period + ".jsonl":      0,
+			"log-" + period + ".1.jsonl":    1,
+			"log-" + period + ".jsonl.gz":   0,
+			"log-" + period + ".4.jsonl.gz": 4,
+		}
+
+		for name, index := range names {
+			date, gotIndex, ok := fs.ParseFilename(name)
+			if !ok {
+				t.Errorf("%s: ParseFilename(%s) not ok", interval, name)
+				continue
+			}
+			if !date.Equal(start) {
+				t.Errorf("%s: ParseFilename(%s) date = %s, want %s", interval, name, date, start)
+			}
+			if gotIndex != index {
+				t.Errorf("%s: ParseFilename(%s) index = %d, want %d", interval, name, gotIndex, index)
+			}
+		}
+	}
+
+	fs := NewFormatService("test")
+	for _, name := range []string{"current.txt", "log-2026-10-16.log", "log-2026-13-45.txt", "other-2026-10-16.txt"} {
+		if _, _, ok := fs.ParseFilename(name); ok {
+			t.Errorf("ParseFilename(%s) ok, want not ok", name)
+		}
+	}
+}
+
+func TestParseFilenameTemplate(t *testing.T) {
+	fs := NewFormatService("api")
+	fs.Interval = types.Hourly
+	fs.Location = time.UTC
+	fs.TxtTemplate = "{client}-{date}-{pid}{index}.log"
+
+	date, index, ok := fs.ParseFilename("api-2026-10-16T15-4242.2.log")
+	if !ok {
+		t.Fatal("ParseFilename not ok")
+	}
+	if want := time.Date(2026, 10, 16, 15, 0, 0, 0, time.UTC); !date.Equal(want) || index != 2 {
+		t.Errorf("ParseFilename = %s %d, want %s 2", date, index, want)
+	}
+
+	if _, _, ok := fs.ParseFilename("web-2026-10-16T15-4242.log"); ok {
+		t.Error("ParseFilename accepted another client")
+	}
+}
+
+func TestFields(t *testing.T) {
+	fields := Fields("user", "ana", types.Field{Key: "id", Value: 7}, 42, "dangling")
+
+	want := types.Fields{
+		{Key: "user", Value: "ana"},
+		{Key: "id", Value: 7},
+		{Key: "!BADKEY", Value: 42},
+		{Key: "!BADKEY", Value: "dangling"},
+	}
+	if len(fields) != len(want) {
+		t.Fatalf("Fields = %v, want %v", fields, want)
+	}
+	for i := range want {
+		if fields[i] != want[i] {
+			t.Errorf("field %d = %v, want %v", i, fields[i], want[i])
+		}
+	}
+}
+
+func TestFieldsText(t *testing.T) {
+	fs := NewFormatService("test")
+
+	got := fs.FieldsText(types.Fields{
+		{Key: "user", Value: "ana"},
+		{Key: "count", Value: 3},
+		{Key: "query", Value: "a = b"},
+		{Key: "quote", Value: `say "hi"`},
+		{Key: "lines", Value: "one\ntwo"},
+		{Key: "empty", Value: ""},
+		{Key: "err", Value: errors.New("not found")},
+	})
+
+	want := ` user=ana count=3 query="a = b" quote="say \"hi\"" lines="one\ntwo" empty="" err="not found"`
+	if got != want {
+		t.Errorf("FieldsText = %s, want %s", got, want)
+	}
+}
+
+func TestJSONLineFields(t *testing.T) {
+	fs := NewFormatService("test")
+
+	line := fs.JSONLine(types.LogEntry{
+		Level:   types.Info,
+		Code:    "HTTP",
+		Message: "request",
+		Fields: types.Fields{
+			{Key: "path", Value: "/users"},
+			{Key: "status", Value: 200},
+			{Key: "err", Value: errors.New("timeout")},
+		},
+	})
+
+	if !strings.Contains(line, `"fields":{"path":"/users","status":200,"err":"timeout"}`) {
+		t.Errorf("fields not written as an ordered JSON object: %s", line)
+	}
+
+	var decoded map[string]any
+	if err := json.Unmarshal([]byte(line), &decoded); err != nil {
+		t.Fatalf("line is not JSON > %v: %s", err, line)
+	}
+
+	if line := fs.JSONLine(types.LogEntry{Level: types.Info, Code: "HTTP"}); strings.Contains(line, `"fields"`) {
+		t.Errorf("fields written for an entry without fields: %s", line)
+	}
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxErrorBody limits how much of an error response body is kept in a statusError
+const maxErrorBody = 512
+
+// statusError is returned by post when the server answers with a non 2xx status
+// After holds the delay requested by a Retry-After header, if any
+type statusError struct {
+	Status int
+	Body   string
+	After  time.Duration
+}
+
+func (e *statusError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("server answered %d %s", e.Status, http.StatusText(e.Status))
+	}
+
+	return fmt.Sprintf("server answered %d %s > %s", e.Status, http.StatusText(e.Status), e.Body)
+}
+
+// permanent reports whether retrying a request that failed with err is pointless
+// Client errors are permanent, except request timeouts (408) and rate limiting (429)
+func permanent(err error) bool {
+	var status *statusError
+	if !errors.As(err, &status) {
+		return false
+	}
+
+	return status.Status >= 400 && status.Status < 500 &&
+		status.Status != http.StatusRequestTimeout &&
+		status.Status != http.StatusTooManyRequests
+}
+
+// retryAfter parses a Retry-After header, given in seconds or as an HTTP date
+// Returns 0 when the header is missing or invalid
+func retryAfter(header string) time.Duration {
+	if header == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(strings.TrimSpace(header)); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(header); err == nil {
+		return max(time.Until(date), 0)
+	}
+
+	return 0
+}
+
+// post sends body to url with the given content type and headers, gzipping it when compress is set
+// Returns the response body, or a statusError when the status is not 2xx
+func post(client *http.Client, url, contentType string, headers map[string]string, body []byte, compress bool) ([]byte, error) {
+	if compress {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(body); err != nil {
+			return nil, fmt.Errorf("error compressing request > %w", err)
+		}
+		if err := zw.Close(); err != nil {
+			return nil, fmt.Errorf("error compressing request > %w", err)
+		}
+		body = buf.Bytes()
+	}
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("error creating request > %w", err)
+	}
+
+	req.Header.Set("Content-Type", contentType)
+	if compress {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error sending request > %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response > %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		text := strings.TrimSpace(string(respBody))
+		if len(text) > maxErrorBody {
+			text = text[:maxErrorBody]
+		}
+
+		return nil, &statusError{
+			Status: resp.StatusCode,
+			Body:   text,
+			After:  retryAfter(resp.Header.Get("Retry-After")),
+		}
+	}
+
+	return respBody, nil
+}
@@ -0,0 +1,189 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	types "github.com/vinialx/vloggo-go/types"
+)
+
+// JournaldSocket is the path of the journald native protocol socket
+const JournaldSocket = "/run/systemd/journal/socket"
+
+// JournaldSink writes entries to systemd-journald using its native protocol
+// Code, caller and structured fields become journal fields next to MESSAGE and PRIORITY
+// Structured fields are named VLOGGO_F_<KEY>, so "user id" is sent as VLOGGO_F_USER_ID
+// Payloads too large for a datagram are passed to journald through a temporary file descriptor
+type JournaldSink struct {
+	socket string
+	client string
+
+	conn *net.UnixConn
+	mu   sync.Mutex
+}
+
+// NewJournaldSink creates a new JournaldSink for client
+// If socket is empty, defaults to JournaldSocket. The socket is opened on the first write
+func NewJournaldSink(socket, client string) *JournaldSink {
+	if socket == "" {
+		socket = JournaldSocket
+	}
+
+	if client == "" {
+		client = "VLoggo"
+	}
+
+	return &JournaldSink{
+		socket: socket,
+		client: client,
+	}
+}
+
+// journalFieldPrefix is prepended to the journal field name of every user field
+// so a field cannot override MESSAGE, PRIORITY, CODE_FILE or the other fields set by the sink
+const journalFieldPrefix = "VLOGGO_F_"
+
+// journalKey makes key a valid journal field name, prefixed with journalFieldPrefix
+// Letters are uppercased and other characters besides digits and '_' become '_'
+// Returns "" for an empty key
+func journalKey(key string) string {
+	if key == "" {
+		return ""
+	}
+
+	name := []byte(strings.ToUpper(key))
+	for i, c := range name {
+		if !(c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_') {
+			name[i] = '_'
+		}
+	}
+
+	out := journalFieldPrefix + string(name)
+	if len(out) > 64 {
+		out = out[:64]
+	}
+
+	return out
+}
+
+// appendJournalField appends one field to a native protocol payload
+// Values containing newlines use the binary form: KEY, newline, little-endian 64-bit length, value, newline
+func appendJournalField(b *bytes.Buffer, key, value string) {
+	if !strings.Contains(value, "\n") {
+		b.WriteString(key + "=" + value + "\n")
+		return
+	}
+
+	b.WriteString(key + "\n")
+	binary.Write(b, binary.LittleEndian, uint64(len(value)))
+	b.WriteString(value + "\n")
+}
+
+// payload formats an entry as a journald native protocol payload
+func (s *JournaldSink) payload(entry types.LogEntry) []byte {
+	var b bytes.Buffer
+
+	appendJournalField(&b, "MESSAGE", entry.Message)
+	appendJournalField(&b, "PRIORITY", strconv.Itoa(syslogSeverity(entry.Level)))
+	appendJournalField(&b, "SYSLOG_IDENTIFIER", s.client)
+	appendJournalField(&b, "VLOGGO_LEVEL", string(entry.Level))
+
+	if entry.Code != "" {
+		appendJournalField(&b, "VLOGGO_CODE", entry.Code)
+	}
+
+	if i := strings.LastIndex(entry.Caller, ":"); i > 0 {
+		appendJournalField(&b, "CODE_FILE", entry.Caller[:i])
+		appendJournalField(&b, "CODE_LINE", entry.Caller[i+1:])
+	}
+
+	for _, field := range entry.Fields {
+		key := journalKey(field.Key)
+		if key == "" {
+			continue
+		}
+
+		value := field.Value
+		if e, ok := value.(error); ok {
+			value = e.Error()
+		}
+		appendJournalField(&b, key, fmt.Sprint(value))
+	}
+
+	return b.Bytes()
+}
+
+// dial opens the journald socket
+// Must be called with s.mu held
+func (s *JournaldSink) dial() error {
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: s.socket, Net: "unixgram"})
+	if err != nil {
+		return fmt.Errorf("error connecting to journald %s > %w", s.socket, err)
+	}
+
+	s.conn = conn
+	return nil
+}
+
+// send writes one payload, falling back to a file descriptor when it does not fit in a datagram
+// Must be called with s.mu held
+func (s *JournaldSink) send(payload []byte) error {
+	_, err := s.conn.Write(payload)
+	if err != nil && tooLarge(err) {
+		return s.sendFile(payload)
+	}
+
+	return err
+}
+
+// Write formats the entry and sends it to journald, reconnecting once if the socket was lost
+func (s *JournaldSink) Write(entry types.LogEntry) error {
+	payload := s.payload(entry)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if s.conn == nil {
+			if err = s.dial(); err != nil {
+				continue
+			}
+		}
+
+		if err = s.send(payload); err == nil {
+			return nil
+		}
+
+		s.conn.Close()
+		s.conn = nil
+	}
+
+	return fmt.Errorf("error writing to journald > %w", err)
+}
+
+// Flush has nothing to do since every entry is sent as it is written
+func (s *JournaldSink) Flush() error {
+	return nil
+}
+
+// Close closes the journald socket
+// A later write opens a new one
+func (s *JournaldSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		return nil
+	}
+
+	err := s.conn.Close()
+	s.conn = nil
+
+	return err
+}
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// tooLarge reports whether a datagram write failed because of the payload size
+func tooLarge(err error) bool {
+	return errors.Is(err, syscall.EMSGSIZE) || errors.Is(err, syscall.ENOBUFS)
+}
+
+// sendFile writes the payload to an unlinked temporary file and passes its descriptor to journald
+// The file is created in /dev/shm when available so it never touches the disk
+// Must be called with s.mu held
+func (s *JournaldSink) sendFile(payload []byte) error {
+	dir := "/dev/shm"
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		dir = os.TempDir()
+	}
+
+	file, err := os.CreateTemp(dir, "vloggo-journal-")
+	if err != nil {
+		return fmt.Errorf("error creating journal payload file > %w", err)
+	}
+	defer file.Close()
+
+	os.Remove(file.Name())
+
+	if _, err := file.Write(payload); err != nil {
+		return fmt.Errorf("error writing journal payload file > %w", err)
+	}
+
+	raw, err := s.conn.SyscallConn()
+	if err != nil {
+		return fmt.Errorf("error passing journal payload file > %w", err)
+	}
+
+	rights := syscall.UnixRights(int(file.Fd()))
+
+	var sendErr error
+	if err := raw.Write(func(fd uintptr) bool {
+		sendErr = syscall.Sendmsg(int(fd), nil, rights, nil, 0)
+		return sendErr != syscall.EAGAIN
+	}); err != nil {
+		return fmt.Errorf("error passing journal payload file > %w", err)
+	}
+	if sendErr != nil {
+		return fmt.Errorf("error passing journal payload file > %w", sendErr)
+	}
+
+	return nil
+}
@@ -0,0 +1,162 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	types "github.com/vinialx/vloggo-go/types"
+)
+
+// journaldSocket listens on a unixgram socket in a temporary folder
+// Returns its path and the listening connection
+func journaldSocket(t *testing.T) (string, *net.UnixConn) {
+	t.Helper()
+
+	// Unix socket paths are limited to about 100 bytes, shorter than some test folders
+	dir, err := os.MkdirTemp("", "vloggo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, "journal.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return path, conn
+}
+
+// parseJournal decodes a native protocol payload into its fields, in order
+func parseJournal(t *testing.T, payload []byte) [][2]string {
+	t.Helper()
+
+	var fields [][2]string
+	for len(payload) > 0 {
+		end := bytes.IndexByte(payload, '\n')
+		if end < 0 {
+			t.Fatalf("field without newline: %q", payload)
+		}
+
+		line := payload[:end]
+		payload = payload[end+1:]
+
+		if key, value, ok := bytes.Cut(line, []byte("=")); ok {
+			fields = append(fields, [2]string{string(key), string(value)})
+			continue
+		}
+
+		if len(payload) < 8 {
+			t.Fatalf("binary field %s without length", line)
+		}
+		size := binary.LittleEndian.Uint64(payload[:8])
+		payload = payload[8:]
+
+		if uint64(len(payload)) < size+1 || payload[size] != '\n' {
+			t.Fatalf("binary field %s with length %d does not end with a newline", line, size)
+		}
+		fields = append(fields, [2]string{string(line), string(payload[:size])})
+		payload = payload[size+1:]
+	}
+
+	return fields
+}
+
+func TestJournaldFields(t *testing.T) {
+	path, conn := journaldSocket(t)
+
+	s := NewJournaldSink(path, "test")
+	defer s.Close()
+
+	err := s.Write(types.LogEntry{
+		Level:   types.Warn,
+		Code:    "DISK",
+		Caller:  "internal/disk.go:42",
+		Message: "disk almost full\nonly 2% left",
+		Fields: types.Fields{
+			{Key: "mount point", Value: "/var"},
+			{Key: "_trusted", Value: "spoofed"},
+			{Key: "message", Value: "overridden"},
+			{Key: "trace", Value: "line one\nline two\n"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 65536)
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := parseJournal(t, buf[:n])
+	want := [][2]string{
+		{"MESSAGE", "disk almost full\nonly 2% left"},
+		{"PRIORITY", "4"},
+		{"SYSLOG_IDENTIFIER", "test"},
+		{"VLOGGO_LEVEL", "WARN"},
+		{"VLOGGO_CODE", "DISK"},
+		{"CODE_FILE", "internal/disk.go"},
+		{"CODE_LINE", "42"},
+		{"VLOGGO_F_MOUNT_POINT", "/var"},
+		{"VLOGGO_F__TRUSTED", "spoofed"},
+		{"VLOGGO_F_MESSAGE", "overridden"},
+		{"VLOGGO_F_TRACE", "line one\nline two\n"},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("got fields %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("field %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+
+	if !bytes.Contains(buf[:n], []byte("MESSAGE\n")) {
+		t.Error("multi-line MESSAGE not sent in the binary form")
+	}
+}
+
+func TestJournaldReconnects(t *testing.T) {
+	path, conn := journaldSocket(t)
+
+	s := NewJournaldSink(path, "test")
+	defer s.Close()
+
+	if err := s.Write(types.LogEntry{Level: types.Info, Message: "before restart"}); err != nil {
+		t.Fatal(err)
+	}
+
+	conn.Close()
+	os.Remove(path)
+
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if err := s.Write(types.LogEntry{Level: types.Info, Message: "after restart"}); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 65536)
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fields := parseJournal(t, buf[:n]); fields[0][1] != "after restart" {
+		t.Errorf("got %q after restart", fields)
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !linux
+
+package services
+
+import (
+	"errors"
+)
+
+// tooLarge always reports false, journald only runs on linux
+func tooLarge(err error) bool {
+	return false
+}
+
+// sendFile is not supported outside linux
+func (s *JournaldSink) sendFile(payload []byte) error {
+	return errors.New("journald payload too large for a datagram")
+}
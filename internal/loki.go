@@ -0,0 +1,171 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	types "github.com/vinialx/vloggo-go/types"
+)
+
+// LokiPushPath is the path of the Loki push API
+const LokiPushPath = "/loki/api/v1/push"
+
+// invalidLabel matches the characters not allowed in a Loki label name
+var invalidLabel = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// LokiSink pushes batches of entries to Grafana Loki as gzipped JSON
+// Entries are grouped in streams labeled by client and level, plus the configured labels
+// Each line is the JSONLine of the entry; rate limited pushes (429) are retried after Retry-After
+// Batches still failing with a temporary error after every attempt are kept for the next push,
+// up to the pending entries limit of the batcher
+type LokiSink struct {
+	cfg      types.Loki
+	url      string
+	labels   map[string]string
+	attempts int
+	http     *http.Client
+	format   *FormatService
+	batcher  *batcher
+}
+
+// lokiStream is one stream of a push request
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+// NewLokiSink creates a new LokiSink for client and starts its batching goroutine
+func NewLokiSink(cfg types.Loki, client string) *LokiSink {
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = defaultBackoff
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultHTTPTimeout
+	}
+
+	url := strings.TrimRight(cfg.URL, "/")
+	if !strings.HasSuffix(url, LokiPushPath) {
+		url += LokiPushPath
+	}
+
+	labels := make(map[string]string, len(cfg.Labels))
+	for key, value := range cfg.Labels {
+		labels[lokiLabel(key)] = value
+	}
+
+	s := &LokiSink{
+		cfg:      cfg,
+		url:      url,
+		labels:   labels,
+		attempts: attempts(cfg.Retries),
+		http:     &http.Client{Timeout: cfg.Timeout},
+		format:   NewFormatService(client),
+	}
+	s.batcher = newBatcher(client, cfg.Batch, cfg.Interval, true, s.send)
+
+	return s
+}
+
+// lokiLabel makes key a valid label name, replacing invalid characters by '_'
+// A leading digit is prefixed with '_'
+func lokiLabel(key string) string {
+	name := invalidLabel.ReplaceAllString(key, "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "_" + name
+	}
+
+	return name
+}
+
+// encode groups a batch into streams, one per level, with the values of each stream in time order
+func (s *LokiSink) encode(batch []types.LogEntry) ([]byte, error) {
+	sorted := make([]types.LogEntry, len(batch))
+	copy(sorted, batch)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Time.Before(sorted[j].Time)
+	})
+
+	streams := make(map[types.LogLevel]*lokiStream)
+	var order []types.LogLevel
+
+	for _, entry := range sorted {
+		stream, ok := streams[entry.Level]
+		if !ok {
+			labels := make(map[string]string, len(s.labels)+2)
+			for key, value := range s.labels {
+				labels[key] = value
+			}
+			labels["client"] = s.format.Client
+			labels["level"] = string(entry.Level)
+
+			stream = &lokiStream{Stream: labels}
+			streams[entry.Level] = stream
+			order = append(order, entry.Level)
+		}
+
+		t := entry.Time
+		if t.IsZero() {
+			t = time.Now()
+		}
+
+		stream.Values = append(stream.Values, [2]string{
+			strconv.FormatInt(t.UnixNano(), 10),
+			strings.TrimSuffix(s.format.JSONLine(entry), "\n"),
+		})
+	}
+
+	push := struct {
+		Streams []*lokiStream `json:"streams"`
+	}{}
+	for _, level := range order {
+		push.Streams = append(push.Streams, streams[level])
+	}
+
+	return json.Marshal(push)
+}
+
+// send pushes a batch with retries
+// The batcher keeps the batch when every attempt failed with a temporary error
+func (s *LokiSink) send(stop <-chan struct{}, batch []types.LogEntry) error {
+	body, err := s.encode(batch)
+	if err != nil {
+		return fmt.Errorf("error encoding %d entries > %w", len(batch), err)
+	}
+
+	err = retry(stop, s.attempts, s.cfg.Backoff, func() error {
+		_, err := post(s.http, s.url, "application/json", s.cfg.Headers, body, true)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("error pushing %d entries to loki > %w", len(batch), err)
+	}
+
+	return nil
+}
+
+// Write queues the entry for the next push
+func (s *LokiSink) Write(entry types.LogEntry) error {
+	return s.batcher.add(entry)
+}
+
+// Dropped returns the number of entries dropped because too many were waiting to be pushed
+func (s *LokiSink) Dropped() uint64 {
+	return s.batcher.dropped.Load()
+}
+
+// Flush pushes the pending entries, keeping those that failed temporarily
+func (s *LokiSink) Flush() error {
+	return s.batcher.flush()
+}
+
+// Close pushes the pending entries and stops the batching goroutine
+// Pending retries are cut short and the entries still failing are dropped
+func (s *LokiSink) Close() error {
+	return s.batcher.close()
+}
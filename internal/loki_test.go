@@ -0,0 +1,218 @@
+package services
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	types "github.com/vinialx/vloggo-go/types"
+)
+
+// lokiServer records the push requests it accepts
+// status decides the answer to each request, numbered from 1, and a 429 asks to retry after a second
+type lokiServer struct {
+	*batchServer
+
+	arrivals []time.Time
+	pushes   [][]lokiStream
+	paths    []string
+	tenants  []string
+}
+
+func newLokiServer(t *testing.T, status func(request int) int) *lokiServer {
+	t.Helper()
+
+	ls := &lokiServer{}
+	ls.batchServer = newBatchServer(t, func(request int, w http.ResponseWriter, r *http.Request) {
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var push struct {
+			Streams []lokiStream `json:"streams"`
+		}
+		if err := json.NewDecoder(zr).Decode(&push); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		ls.arrivals = append(ls.arrivals, time.Now())
+		code := status(request)
+		if code == http.StatusNoContent {
+			ls.pushes = append(ls.pushes, push.Streams)
+			ls.paths = append(ls.paths, r.URL.Path)
+			ls.tenants = append(ls.tenants, r.Header.Get("X-Scope-OrgID"))
+		}
+
+		if code == http.StatusTooManyRequests {
+			w.Header().Set("Retry-After", "1")
+		}
+		w.WriteHeader(code)
+	})
+
+	return ls
+}
+
+// newTestLoki creates a LokiSink pushing to url, see newTestSink
+func newTestLoki(t *testing.T, url string, edit func(cfg *types.Loki)) *LokiSink {
+	t.Helper()
+
+	return newTestSink(t, types.Loki{
+		URL:      url,
+		Interval: time.Hour,
+		Backoff:  time.Millisecond,
+	}, edit, func(cfg types.Loki) *LokiSink {
+		return NewLokiSink(cfg, "test")
+	})
+}
+
+func TestLokiStreams(t *testing.T) {
+	ls := newLokiServer(t, func(int) int { return http.StatusNoContent })
+	s := newTestLoki(t, ls.URL+"/", func(cfg *types.Loki) {
+		cfg.Labels = map[string]string{"env-name": "prod", "1zone": "a"}
+		cfg.Headers = map[string]string{"X-Scope-OrgID": "tenant"}
+	})
+
+	start := time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC)
+	s.Write(types.LogEntry{Level: types.Info, Message: "second", Time: start.Add(time.Second)})
+	s.Write(types.LogEntry{Level: types.Error, Message: "failure", Time: start.Add(2 * time.Second)})
+	s.Write(types.LogEntry{Level: types.Info, Message: "first", Time: start})
+	if err := s.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	if len(ls.pushes) != 1 {
+		t.Fatalf("got %d pushes, want 1", len(ls.pushes))
+	}
+	if ls.paths[0] != LokiPushPath || ls.tenants[0] != "tenant" {
+		t.Errorf("pushed to %q with tenant %q", ls.paths[0], ls.tenants[0])
+	}
+
+	streams := ls.pushes[0]
+	if len(streams) != 2 {
+		t.Fatalf("got %d streams, want one per level", len(streams))
+	}
+
+	for _, stream := range streams {
+		labels := stream.Stream
+		if labels["client"] != "test" || labels["env_name"] != "prod" || labels["_1zone"] != "a" || len(labels) != 4 {
+			t.Errorf("wrong labels %v", labels)
+		}
+
+		switch labels["level"] {
+		case "INFO":
+			if len(stream.Values) != 2 ||
+				stream.Values[0][0] != "1792144800000000000" ||
+				stream.Values[1][0] != "1792144801000000000" {
+				t.Errorf("INFO values not in time order: %v", stream.Values)
+			}
+
+			var line struct {
+				Message string `json:"message"`
+			}
+			if err := json.Unmarshal([]byte(stream.Values[0][1]), &line); err != nil || line.Message != "first" {
+				t.Errorf("line %q is not the JSONLine of the first entry", stream.Values[0][1])
+			}
+		case "ERROR":
+			if len(stream.Values) != 1 {
+				t.Errorf("got %d ERROR values, want 1", len(stream.Values))
+			}
+		default:
+			t.Errorf("unexpected stream %v", labels)
+		}
+	}
+}
+
+func TestLokiRetriesAfter429(t *testing.T) {
+	ls := newLokiServer(t, func(request int) int {
+		if request == 1 {
+			return http.StatusTooManyRequests
+		}
+		return http.StatusNoContent
+	})
+	s := newTestLoki(t, ls.URL, nil)
+
+	s.Write(types.LogEntry{Level: types.Info, Message: "rate limited"})
+	if err := s.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	if ls.requests != 2 || len(ls.pushes) != 1 {
+		t.Fatalf("got %d requests and %d pushes, want 2 and 1", ls.requests, len(ls.pushes))
+	}
+	if wait := ls.arrivals[1].Sub(ls.arrivals[0]); wait < 900*time.Millisecond {
+		t.Errorf("retried after %s, want the 1s Retry-After", wait)
+	}
+}
+
+func TestLokiKeepsFailedBatch(t *testing.T) {
+	var mu sync.Mutex
+	down := true
+
+	ls := newLokiServer(t, func(int) int {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if down {
+			return http.StatusServiceUnavailable
+		}
+		return http.StatusNoContent
+	})
+	s := newTestLoki(t, ls.URL, func(cfg *types.Loki) {
+		cfg.Retries = -1
+	})
+
+	s.Write(types.LogEntry{Level: types.Info, Message: "kept"})
+	if err := s.Flush(); err == nil {
+		t.Fatal("Flush succeeded while the server was failing")
+	}
+
+	mu.Lock()
+	down = false
+	mu.Unlock()
+
+	s.Write(types.LogEntry{Level: types.Info, Message: "next"})
+	if err := s.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	if len(ls.pushes) != 1 || len(ls.pushes[0]) != 1 || len(ls.pushes[0][0].Values) != 2 {
+		t.Fatalf("got pushes %v, want the kept entry pushed with the next one", ls.pushes)
+	}
+	if s.Dropped() != 0 {
+		t.Errorf("dropped %d entries, want 0", s.Dropped())
+	}
+}
+
+func TestRetryDelayCapsRetryAfter(t *testing.T) {
+	limited := &statusError{Status: http.StatusTooManyRequests, After: time.Hour}
+	if delay := retryDelay(1, time.Second, limited); delay != maxBackoff {
+		t.Errorf("Retry-After of an hour waited %s, want %s", delay, maxBackoff)
+	}
+
+	limited.After = 2 * time.Second
+	if delay := retryDelay(1, time.Second, limited); delay != 2*time.Second {
+		t.Errorf("Retry-After of 2s waited %s", delay)
+	}
+
+	if delay := retryDelay(3, time.Second, nil); delay != 4*time.Second {
+		t.Errorf("third retry waited %s, want 4s", delay)
+	}
+	if delay := retryDelay(20, time.Second, nil); delay != maxBackoff {
+		t.Errorf("backoff not capped, waited %s", delay)
+	}
+}
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"io"
+	"sync"
+
+	types "github.com/vinialx/vloggo-go/types"
+)
+
+// TxtSink writes entries as text lines to the txt log file of a FileService
+type TxtSink struct {
+	file   *FileService
+	format *FormatService
+}
+
+// NewTxtSink creates a new TxtSink writing through file
+func NewTxtSink(file *FileService, client string) *TxtSink {
+	return &TxtSink{
+		file:   file,
+		format: NewFormatService(client),
+	}
+}
+
+// Write formats the entry with FormatService.Line and writes it to the txt file
+func (s *TxtSink) Write(entry types.LogEntry) error {
+	return s.file.WriteTxt(entry.Level, s.format.Line(entry))
+}
+
+// Flush writes the buffered lines of the FileService to disk
+func (s *TxtSink) Flush() error {
+	return s.file.Flush()
+}
+
+// Close closes the FileService, shared with the JSONSink of the same instance
+func (s *TxtSink) Close() error {
+	return s.file.Close()
+}
+
+// JSONSink writes entries as JSON lines to the json log file of a FileService
+type JSONSink struct {
+	file   *FileService
+	format *FormatService
+}
+
+// NewJSONSink creates a new JSONSink writing through file
+func NewJSONSink(file *FileService, client string) *JSONSink {
+	return &JSONSink{
+		file:   file,
+		format: NewFormatService(client),
+	}
+}
+
+// Write formats the entry with FormatService.JSONLine and writes it to the json file
+func (s *JSONSink) Write(entry types.LogEntry) error {
+	return s.file.WriteJSON(entry.Level, s.format.JSONLine(entry))
+}
+
+// Flush writes the buffered lines of the FileService to disk
+func (s *JSONSink) Flush() error {
+	return s.file.Flush()
+}
+
+// Close closes the FileService, shared with the TxtSink of the same instance
+func (s *JSONSink) Close() error {
+	return s.file.Close()
+}
+
+// WriterSink writes entries as text or JSON lines to an io.Writer
+// Writes are serialized so w does not need to be safe for concurrent use
+type WriterSink struct {
+	w          io.Writer
+	jsonFormat bool
+	format     *FormatService
+	mu         sync.Mutex
+}
+
+// NewWriterSink creates a new WriterSink writing to w
+// Lines are formatted with FormatService.JSONLine when format is types.JSON, FormatService.Line otherwise
+func NewWriterSink(w io.Writer, format types.Format, client string) *WriterSink {
+	return &WriterSink{
+		w:          w,
+		jsonFormat: format == types.JSON,
+		format:     NewFormatService(client),
+	}
+}
+
+// Write formats the entry and writes it to w in a single call
+func (s *WriterSink) Write(entry types.LogEntry) error {
+	line := s.format.Line(entry)
+	if s.jsonFormat {
+		line = s.format.JSONLine(entry)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := io.WriteString(s.w, line)
+	return err
+}
+
+// Flush flushes w when it provides a Flush method, such as a bufio.Writer
+func (s *WriterSink) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if f, ok := s.w.(interface{ Flush() error }); ok {
+		return f.Flush()
+	}
+
+	return nil
+}
+
+// Close flushes w and leaves it open, it is not owned by the sink
+func (s *WriterSink) Close() error {
+	return s.Flush()
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	types "github.com/vinialx/vloggo-go/types"
+)
+
+func TestWriterSinkText(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewWriterSink(&buf, types.Text, "test")
+
+	at := time.Date(2026, 10, 16, 10, 0, 0, 0, time.Local)
+	if err := sink.Write(types.LogEntry{Time: at, Level: types.Warn, Code: "DISK", Caller: "main.go:10", Message: "almost full"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "[test] [16/10/2026 10:00:00] [WARN] [DISK] [main.go:10] : almost full\n"; buf.String() != want {
+		t.Errorf("wrote %q, want %q", buf.String(), want)
+	}
+}
+
+func TestWriterSinkJSON(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewWriterSink(&buf, types.JSON, "test")
+
+	if err := sink.Write(types.LogEntry{Time: time.Now(), Level: types.Error, Code: "DB", Message: "connection lost"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var line map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("line is not JSON > %v: %q", err, buf.String())
+	}
+	if line["client"] != "test" || line["level"] != "ERROR" || line["code"] != "DB" || line["message"] != "connection lost" {
+		t.Errorf("unexpected JSON line %q", buf.String())
+	}
+}
+
+func TestWriterSinkFlushesBufferedWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	sink := NewWriterSink(w, types.Text, "test")
+
+	if err := sink.Write(types.LogEntry{Level: types.Info, Code: "BUF", Message: "buffered"}); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Fatal("bufio.Writer flushed before Flush")
+	}
+
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "buffered") {
+		t.Errorf("Close did not flush the writer: %q", buf.String())
+	}
+}
+
+func TestWriterSinkConcurrentWrites(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewWriterSink(&buf, types.JSON, "test")
+
+	const writers, lines = 8, 100
+	message := strings.Repeat("m", 200)
+
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < lines; j++ {
+				if err := sink.Write(types.LogEntry{Level: types.Info, Code: "RACE", Message: message}); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	got := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(got) != writers*lines {
+		t.Fatalf("wrote %d lines, want %d", len(got), writers*lines)
+	}
+	for _, line := range got {
+		if !json.Valid([]byte(line)) {
+			t.Fatalf("interleaved line %q", line)
+		}
+	}
+}
@@ -0,0 +1,300 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	types "github.com/vinialx/vloggo-go/types"
+)
+
+// Local syslog sockets, tried in order when no network is configured
+var syslogSockets = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// syslogTimeout bounds dialing and writing to the syslog server
+const syslogTimeout = 5 * time.Second
+
+// syslogBackoff is the first delay before dialing again after the syslog server could not be reached
+// It doubles after each failed dial, up to maxBackoff
+const syslogBackoff = time.Second
+
+// syslogSDID is the SD-ID of the structured data element holding caller and fields
+// 32473 is the private enterprise number reserved for documentation (RFC 5612)
+const syslogSDID = "vloggo@32473"
+
+// syslogUser is the default facility (user-level messages)
+const syslogUser = 1
+
+// SyslogSink writes entries to a syslog server in RFC 5424 format
+// Messages are framed with octet counting over TCP, newline terminated on local stream sockets
+// and sent one per datagram otherwise
+// A failed write closes the connection and dials it again before retrying once
+// After a failed dial, writes fail right away until the backoff delay has passed,
+// so a server that is down does not make every log call wait for a dial
+// Over TCP, the first message written after the server closed the connection may be lost,
+// since the broken connection is only reported on the following write
+type SyslogSink struct {
+	network  string
+	address  string
+	facility int
+
+	client   string
+	hostname string
+	pid      string
+
+	conn     net.Conn
+	dialed   string
+	failures int
+	retryAt  time.Time
+	mu       sync.Mutex
+}
+
+// NewSyslogSink creates a new SyslogSink for client
+// The connection is opened on the first write
+func NewSyslogSink(cfg types.Syslog, client string) *SyslogSink {
+	facility := cfg.Facility
+	if facility <= 0 || facility > 23 {
+		facility = syslogUser
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+
+	if client == "" {
+		client = "VLoggo"
+	}
+
+	return &SyslogSink{
+		network:  cfg.Network,
+		address:  cfg.Address,
+		facility: facility,
+		client:   syslogName(client, 48),
+		hostname: syslogName(hostname, 255),
+		pid:      strconv.Itoa(os.Getpid()),
+	}
+}
+
+// syslogSeverity maps a log level to its syslog severity
+func syslogSeverity(level types.LogLevel) int {
+	switch level {
+	case types.Debug:
+		return 7
+	case types.Warn:
+		return 4
+	case types.Error:
+		return 3
+	case types.Fatal:
+		return 2
+	default:
+		return 6
+	}
+}
+
+// syslogName keeps the printable US-ASCII characters of s, without spaces, up to limit characters
+// Returns the nil value "-" when nothing is left
+func syslogName(s string, limit int) string {
+	var b strings.Builder
+
+	for i := 0; i < len(s) && b.Len() < limit; i++ {
+		if s[i] >= 33 && s[i] <= 126 {
+			b.WriteByte(s[i])
+		}
+	}
+
+	if b.Len() == 0 {
+		return "-"
+	}
+
+	return b.String()
+}
+
+// sdName makes key a valid SD-NAME, replacing '=', ']', '"' and non printable characters by '_'
+func sdName(key string) string {
+	name := []byte(syslogName(key, 32))
+	for i, c := range name {
+		if c == '=' || c == ']' || c == '"' {
+			name[i] = '_'
+		}
+	}
+
+	return string(name)
+}
+
+// sdValue escapes '"', '\' and ']' in a PARAM-VALUE
+func sdValue(value any) string {
+	if e, ok := value.(error); ok {
+		value = e.Error()
+	}
+
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(fmt.Sprint(value))
+}
+
+// message formats an entry as an RFC 5424 syslog message
+// Format: <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID [vloggo@32473 caller="..." k="v"] MSG
+func (s *SyslogSink) message(entry types.LogEntry) string {
+	t := entry.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+
+	msgid := "-"
+	if entry.Code != "" {
+		msgid = syslogName(entry.Code, 32)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "<%d>1 %s %s %s %s %s ",
+		s.facility*8+syslogSeverity(entry.Level),
+		t.Format("2006-01-02T15:04:05.000000Z07:00"),
+		s.hostname,
+		s.client,
+		s.pid,
+		msgid,
+	)
+
+	if entry.Caller == "" && len(entry.Fields) == 0 {
+		b.WriteString("-")
+	} else {
+		b.WriteString("[" + syslogSDID)
+		if entry.Caller != "" {
+			b.WriteString(` caller="` + sdValue(entry.Caller) + `"`)
+		}
+		for _, field := range entry.Fields {
+			b.WriteString(" " + sdName(field.Key) + `="` + sdValue(field.Value) + `"`)
+		}
+		b.WriteString("]")
+	}
+
+	if entry.Message != "" {
+		b.WriteString(" " + entry.Message)
+	}
+
+	return b.String()
+}
+
+// dial opens the connection to the syslog server
+// Without a network, tries the local sockets as datagram then stream sockets
+// Must be called with s.mu held
+func (s *SyslogSink) dial() error {
+	if s.network != "" {
+		conn, err := net.DialTimeout(s.network, s.address, syslogTimeout)
+		if err != nil {
+			return fmt.Errorf("error connecting to syslog %s %s > %w", s.network, s.address, err)
+		}
+		s.conn, s.dialed = conn, s.network
+		return nil
+	}
+
+	sockets := syslogSockets
+	if s.address != "" {
+		sockets = []string{s.address}
+	}
+
+	var errs []error
+	for _, path := range sockets {
+		for _, network := range []string{"unixgram", "unix"} {
+			conn, err := net.DialTimeout(network, path, syslogTimeout)
+			if err == nil {
+				s.conn, s.dialed = conn, network
+				return nil
+			}
+			errs = append(errs, err)
+		}
+	}
+
+	return fmt.Errorf("error connecting to local syslog > %w", errors.Join(errs...))
+}
+
+// frame prepares a message for the connection
+// TCP uses octet counting, local stream sockets a trailing newline and datagrams are sent as is
+// Must be called with s.mu held
+func (s *SyslogSink) frame(msg string) string {
+	switch s.dialed {
+	case "tcp", "tcp4", "tcp6":
+		return strconv.Itoa(len(msg)) + " " + msg
+	case "unix":
+		return msg + "\n"
+	default:
+		return msg
+	}
+}
+
+// connect dials the syslog server unless a previous dial failed less than the backoff delay ago
+// Must be called with s.mu held
+func (s *SyslogSink) connect() error {
+	if wait := time.Until(s.retryAt); wait > 0 {
+		return fmt.Errorf("syslog unreachable, next attempt in %s", wait.Round(time.Millisecond))
+	}
+
+	if err := s.dial(); err != nil {
+		s.failures++
+		s.retryAt = time.Now().Add(retryDelay(s.failures, syslogBackoff, nil))
+		return err
+	}
+
+	s.failures = 0
+	s.retryAt = time.Time{}
+	return nil
+}
+
+// send writes one message, reconnecting once if the connection was lost
+// Must be called with s.mu held
+func (s *SyslogSink) send(msg string) error {
+	var err error
+
+	for attempt := 0; attempt < 2; attempt++ {
+		if s.conn == nil {
+			if err = s.connect(); err != nil {
+				break
+			}
+		}
+
+		s.conn.SetWriteDeadline(time.Now().Add(syslogTimeout))
+		if _, err = s.conn.Write([]byte(s.frame(msg))); err == nil {
+			return nil
+		}
+
+		s.conn.Close()
+		s.conn = nil
+	}
+
+	return fmt.Errorf("error writing to syslog > %w", err)
+}
+
+// Write formats the entry and sends it to the syslog server
+func (s *SyslogSink) Write(entry types.LogEntry) error {
+	msg := s.message(entry)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.send(msg)
+}
+
+// Flush has nothing to do since every message is sent as it is written
+func (s *SyslogSink) Flush() error {
+	return nil
+}
+
+// Close closes the connection to the syslog server
+// A later write opens a new one
+func (s *SyslogSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		return nil
+	}
+
+	err := s.conn.Close()
+	s.conn = nil
+
+	return err
+}
@@ -0,0 +1,221 @@
+package services
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	types "github.com/vinialx/vloggo-go/types"
+)
+
+// syslogTCP listens on addr and sends every octet-counted frame it reads to frames
+// A frame that does not start with its length is sent prefixed with "BAD FRAME"
+// Returns the listener address and a function closing the listener and every accepted connection
+func syslogTCP(t *testing.T, addr string, frames chan<- string) (string, func()) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var conns []net.Conn
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+
+			mu.Lock()
+			conns = append(conns, conn)
+			mu.Unlock()
+
+			go func() {
+				defer conn.Close()
+
+				r := bufio.NewReader(conn)
+				for {
+					size, err := r.ReadString(' ')
+					if err != nil {
+						return
+					}
+
+					n, err := strconv.Atoi(strings.TrimSuffix(size, " "))
+					if err != nil {
+						frames <- "BAD FRAME " + size
+						return
+					}
+
+					msg := make([]byte, n)
+					if _, err := io.ReadFull(r, msg); err != nil {
+						frames <- "BAD FRAME " + err.Error()
+						return
+					}
+					frames <- string(msg)
+				}
+			}()
+		}
+	}()
+
+	stop := func() {
+		ln.Close()
+
+		mu.Lock()
+		defer mu.Unlock()
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}
+	t.Cleanup(stop)
+
+	return ln.Addr().String(), stop
+}
+
+// nextFrame waits for the next frame
+func nextFrame(t *testing.T, frames <-chan string) string {
+	t.Helper()
+
+	select {
+	case frame := <-frames:
+		if strings.HasPrefix(frame, "BAD FRAME") {
+			t.Fatal(frame)
+		}
+		return frame
+	case <-time.After(2 * time.Second):
+		t.Fatal("no syslog message received")
+		return ""
+	}
+}
+
+func TestSyslogTCPOctetCounting(t *testing.T) {
+	frames := make(chan string, 16)
+	addr, _ := syslogTCP(t, "127.0.0.1:0", frames)
+
+	s := NewSyslogSink(types.Syslog{Network: "tcp", Address: addr, Facility: 16}, "my app")
+	defer s.Close()
+
+	messages := []string{"first message", "second message\nspanning two lines", "third"}
+	for _, message := range messages {
+		if err := s.Write(types.LogEntry{Level: types.Error, Code: "DB", Message: message}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, message := range messages {
+		frame := nextFrame(t, frames)
+		if !strings.HasPrefix(frame, "<131>1 ") {
+			t.Errorf("wrong PRI or version in %q", frame)
+		}
+		if !strings.Contains(frame, " myapp "+s.pid+" DB ") {
+			t.Errorf("wrong APP-NAME, PROCID or MSGID in %q", frame)
+		}
+		if !strings.HasSuffix(frame, " "+message) {
+			t.Errorf("frame %q does not end with %q", frame, message)
+		}
+	}
+}
+
+func TestSyslogStructuredDataEscaping(t *testing.T) {
+	s := NewSyslogSink(types.Syslog{Network: "udp", Address: "127.0.0.1:514"}, "test")
+
+	msg := s.message(types.LogEntry{
+		Level:   types.Info,
+		Caller:  "main.go:10",
+		Message: "done",
+		Fields: types.Fields{
+			{Key: "quote", Value: `say "hi"`},
+			{Key: "path", Value: `C:\logs`},
+			{Key: "bracket", Value: "a]b"},
+			{Key: `bad key="x"]`, Value: "v"},
+			{Key: "err", Value: errors.New("boom")},
+		},
+	})
+
+	want := `[vloggo@32473 caller="main.go:10" quote="say \"hi\"" path="C:\\logs" bracket="a\]b" badkey__x__="v" err="boom"] done`
+	if !strings.HasSuffix(msg, want) {
+		t.Errorf("structured data\n got %q\nwant suffix %q", msg, want)
+	}
+
+	empty := s.message(types.LogEntry{Level: types.Info, Message: "plain"})
+	if !strings.HasSuffix(empty, " - - plain") {
+		t.Errorf("nil MSGID and STRUCTURED-DATA not used in %q", empty)
+	}
+}
+
+func TestSyslogReconnects(t *testing.T) {
+	frames := make(chan string, 64)
+	addr, stop := syslogTCP(t, "127.0.0.1:0", frames)
+
+	s := NewSyslogSink(types.Syslog{Network: "tcp", Address: addr}, "test")
+	defer s.Close()
+
+	if err := s.Write(types.LogEntry{Level: types.Info, Message: "before restart"}); err != nil {
+		t.Fatal(err)
+	}
+	nextFrame(t, frames)
+
+	stop()
+	syslogTCP(t, addr, frames)
+
+	// The first writes after the restart may go to the broken connection, see SyslogSink
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		s.Write(types.LogEntry{Level: types.Info, Message: "after restart"})
+
+		select {
+		case frame := <-frames:
+			if strings.HasSuffix(frame, "after restart") {
+				return
+			}
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+
+	t.Fatal("sink did not reconnect after the listener restarted")
+}
+
+func TestSyslogBacksOffWhileDown(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	s := NewSyslogSink(types.Syslog{Network: "tcp", Address: addr}, "test")
+	defer s.Close()
+
+	if err := s.Write(types.LogEntry{Level: types.Info, Message: "server down"}); err == nil {
+		t.Fatal("write succeeded with the server down")
+	}
+
+	frames := make(chan string, 64)
+	syslogTCP(t, addr, frames)
+
+	start := time.Now()
+	err = s.Write(types.LogEntry{Level: types.Info, Message: "inside the backoff"})
+	if err == nil || !strings.Contains(err.Error(), "next attempt in") {
+		t.Fatalf("write inside the backoff window returned %v, want it to fail without dialing", err)
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("write inside the backoff window took %s", elapsed)
+	}
+
+	time.Sleep(syslogBackoff)
+
+	if err := s.Write(types.LogEntry{Level: types.Info, Message: "after the backoff"}); err != nil {
+		t.Fatal(err)
+	}
+	if frame := nextFrame(t, frames); !strings.HasSuffix(frame, "after the backoff") {
+		t.Errorf("got frame %q", frame)
+	}
+}
@@ -0,0 +1,195 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	types "github.com/vinialx/vloggo-go/types"
+)
+
+// spoolExt is the extension of spooled webhook batches
+const spoolExt = ".json"
+
+// rejectedExt is appended to spooled batches the server refused, so they are not replayed automatically
+const rejectedExt = ".rejected"
+
+// WebhookSink posts batches of entries to an HTTP endpoint as a JSON array of JSONLine objects
+// Failed batches are retried with exponential backoff, then spooled to disk
+// Spooled batches are replayed, oldest first, after the next successful send
+// Batches refused with a client error are spooled with rejectedExt and left for the user to replay,
+// once the URL or credentials are fixed
+type WebhookSink struct {
+	cfg      types.Webhook
+	client   string
+	spool    string
+	attempts int
+	http     *http.Client
+	format   *FormatService
+	batcher  *batcher
+}
+
+// NewWebhookSink creates a new WebhookSink for client and starts its batching goroutine
+// Batches are spooled to cfg.Spool, or to a "webhook" folder in dir when it is empty
+func NewWebhookSink(cfg types.Webhook, client, dir string) *WebhookSink {
+	spool := cfg.Spool
+	if spool == "" {
+		spool = filepath.Join(dir, "webhook")
+	}
+
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = defaultBackoff
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultHTTPTimeout
+	}
+
+	s := &WebhookSink{
+		cfg:      cfg,
+		client:   client,
+		spool:    spool,
+		attempts: attempts(cfg.Retries),
+		http:     &http.Client{Timeout: cfg.Timeout},
+		format:   NewFormatService(client),
+	}
+	s.batcher = newBatcher(client, cfg.Batch, cfg.Interval, false, s.send)
+
+	return s
+}
+
+// encode formats a batch as a JSON array of JSONLine objects
+func (s *WebhookSink) encode(batch []types.LogEntry) []byte {
+	lines := make([]string, len(batch))
+	for i, entry := range batch {
+		lines[i] = strings.TrimSuffix(s.format.JSONLine(entry), "\n")
+	}
+
+	return []byte("[" + strings.Join(lines, ",") + "]")
+}
+
+// post sends an encoded batch once
+func (s *WebhookSink) post(body []byte) error {
+	_, err := post(s.http, s.cfg.URL, "application/json", s.cfg.Headers, body, s.cfg.Gzip)
+	return err
+}
+
+// send posts a batch with retries, spooling it when every attempt failed
+// Batches refused with a client error are spooled as rejected, since replaying them would fail
+// until the configuration is fixed
+func (s *WebhookSink) send(stop <-chan struct{}, batch []types.LogEntry) error {
+	body := s.encode(batch)
+
+	err := retry(stop, s.attempts, s.cfg.Backoff, func() error {
+		return s.post(body)
+	})
+	if err == nil {
+		s.replay()
+		return nil
+	}
+
+	rejected := permanent(err)
+
+	path, spoolErr := s.spoolBatch(body, rejected)
+	if spoolErr != nil {
+		return errors.Join(fmt.Errorf("error sending %d entries > %w", len(batch), err), spoolErr)
+	}
+
+	if rejected {
+		return fmt.Errorf("webhook rejected %d entries, spooled to %s > %w", len(batch), path, err)
+	}
+
+	return fmt.Errorf("error sending %d entries, spooled to %s > %w", len(batch), path, err)
+}
+
+// spoolBatch writes an encoded batch to the spool folder, with rejectedExt when rejected is set
+// The file is written under a temporary name and renamed, so replay never reads a partial batch
+func (s *WebhookSink) spoolBatch(body []byte, rejected bool) (string, error) {
+	if err := os.MkdirAll(s.spool, 0755); err != nil {
+		return "", fmt.Errorf("error creating spool folder > %w", err)
+	}
+
+	path := filepath.Join(s.spool, fmt.Sprintf("webhook-%d%s", time.Now().UnixNano(), spoolExt))
+	if rejected {
+		path += rejectedExt
+	}
+	tmpPath := path + ".tmp"
+
+	if err := os.WriteFile(tmpPath, body, 0644); err != nil {
+		os.Remove(tmpPath)
+		return "", fmt.Errorf("error writing spooled batch > %w", err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return "", fmt.Errorf("error renaming spooled batch > %w", err)
+	}
+
+	return path, nil
+}
+
+// replay posts the spooled batches, oldest first, removing each one once delivered
+// Stops at the first failure, batches refused with a client error are renamed with rejectedExt
+func (s *WebhookSink) replay() {
+	files, err := os.ReadDir(s.spool)
+	if err != nil {
+		return
+	}
+
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != spoolExt {
+			continue
+		}
+
+		path := filepath.Join(s.spool, file.Name())
+		body, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+
+		err = s.post(body)
+		if err == nil {
+			os.Remove(path)
+			continue
+		}
+
+		if permanent(err) {
+			os.Rename(path, path+rejectedExt)
+		}
+
+		fmt.Printf("[VLoggo] > [%s] [%s] [ERROR] : failed to replay spooled batch %s > %v\n",
+			s.client,
+			s.format.Date(),
+			path,
+			err,
+		)
+
+		if !permanent(err) {
+			return
+		}
+	}
+}
+
+// Write queues the entry for the next batch
+func (s *WebhookSink) Write(entry types.LogEntry) error {
+	return s.batcher.add(entry)
+}
+
+// Dropped returns the number of entries dropped because too many were waiting to be sent
+func (s *WebhookSink) Dropped() uint64 {
+	return s.batcher.dropped.Load()
+}
+
+// Flush sends the pending entries, retrying and spooling them as needed
+func (s *WebhookSink) Flush() error {
+	return s.batcher.flush()
+}
+
+// Close sends the pending entries and stops the batching goroutine
+// Pending retries are cut short and their batches spooled
+func (s *WebhookSink) Close() error {
+	return s.batcher.close()
+}
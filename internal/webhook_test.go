@@ -0,0 +1,281 @@
+package services
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	types "github.com/vinialx/vloggo-go/types"
+)
+
+// webhookServer records the messages of every batch it accepts
+// status decides the answer to each request, numbered from 1
+type webhookServer struct {
+	*batchServer
+
+	messages []string
+}
+
+func newWebhookServer(t *testing.T, status func(request int) int) *webhookServer {
+	t.Helper()
+
+	ws := &webhookServer{}
+	ws.batchServer = newBatchServer(t, func(request int, w http.ResponseWriter, r *http.Request) {
+		var body io.Reader = r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			zr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			body = zr
+		}
+
+		var batch []struct {
+			Client  string `json:"client"`
+			Message string `json:"message"`
+		}
+		if err := json.NewDecoder(body).Decode(&batch); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		code := status(request)
+		if code == http.StatusOK {
+			for _, entry := range batch {
+				ws.messages = append(ws.messages, entry.Message)
+			}
+		}
+
+		w.WriteHeader(code)
+	})
+
+	return ws
+}
+
+// received returns the number of requests and the accepted messages
+func (ws *webhookServer) received() (int, []string) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	return ws.requests, append([]string(nil), ws.messages...)
+}
+
+// spooled lists the files of the spool folder
+func spooled(t *testing.T, dir string) []string {
+	t.Helper()
+
+	files, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, file := range files {
+		names = append(names, file.Name())
+	}
+	return names
+}
+
+// newTestWebhook creates a WebhookSink posting to url, see newTestSink
+// Returns the sink and its spool folder
+func newTestWebhook(t *testing.T, url string, edit func(cfg *types.Webhook)) (*WebhookSink, string) {
+	t.Helper()
+
+	spool := filepath.Join(t.TempDir(), "spool")
+	s := newTestSink(t, types.Webhook{
+		URL:      url,
+		Interval: time.Hour,
+		Retries:  2,
+		Backoff:  time.Millisecond,
+		Spool:    spool,
+	}, edit, func(cfg types.Webhook) *WebhookSink {
+		return NewWebhookSink(cfg, "test", "")
+	})
+
+	return s, spool
+}
+
+func TestWebhookGzip(t *testing.T) {
+	var encoding, auth string
+	var mu sync.Mutex
+
+	ws := newWebhookServer(t, func(int) int { return http.StatusOK })
+	handler := ws.Config.Handler
+	ws.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		encoding, auth = r.Header.Get("Content-Encoding"), r.Header.Get("Authorization")
+		mu.Unlock()
+		handler.ServeHTTP(w, r)
+	})
+
+	s, _ := newTestWebhook(t, ws.URL, func(cfg *types.Webhook) {
+		cfg.Gzip = true
+		cfg.Headers = map[string]string{"Authorization": "Bearer token"}
+	})
+
+	s.Write(types.LogEntry{Level: types.Info, Message: "first"})
+	s.Write(types.LogEntry{Level: types.Error, Message: "second"})
+	if err := s.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if encoding != "gzip" || auth != "Bearer token" {
+		t.Errorf("got Content-Encoding %q and Authorization %q", encoding, auth)
+	}
+
+	requests, messages := ws.received()
+	if requests != 1 || strings.Join(messages, ",") != "first,second" {
+		t.Errorf("got %d requests with %q, want 1 with first,second", requests, messages)
+	}
+}
+
+func TestWebhookRetriesThenSpools(t *testing.T) {
+	ws := newWebhookServer(t, func(int) int { return http.StatusServiceUnavailable })
+	s, spool := newTestWebhook(t, ws.URL, nil)
+
+	s.Write(types.LogEntry{Level: types.Error, Message: "lost connection"})
+	if err := s.Flush(); err == nil {
+		t.Fatal("Flush succeeded while the server was failing")
+	}
+
+	if requests, _ := ws.received(); requests != 3 {
+		t.Errorf("got %d requests, want 3 with 2 retries", requests)
+	}
+
+	files := spooled(t, spool)
+	if len(files) != 1 || filepath.Ext(files[0]) != spoolExt {
+		t.Fatalf("spooled %q, want one %s batch", files, spoolExt)
+	}
+
+	body, err := os.ReadFile(filepath.Join(spool, files[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(body), `"message":"lost connection"`) {
+		t.Errorf("spooled batch %s misses the entry", body)
+	}
+}
+
+func TestWebhookReplay(t *testing.T) {
+	ws := newWebhookServer(t, func(request int) int {
+		if request == 1 {
+			return http.StatusInternalServerError
+		}
+		return http.StatusOK
+	})
+	s, spool := newTestWebhook(t, ws.URL, func(cfg *types.Webhook) {
+		cfg.Retries = -1
+	})
+
+	s.Write(types.LogEntry{Level: types.Error, Message: "spooled"})
+	if err := s.Flush(); err == nil {
+		t.Fatal("Flush succeeded while the server was failing")
+	}
+	if files := spooled(t, spool); len(files) != 1 {
+		t.Fatalf("spooled %q, want one batch", files)
+	}
+
+	s.Write(types.LogEntry{Level: types.Info, Message: "sent"})
+	if err := s.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	_, messages := ws.received()
+	if strings.Join(messages, ",") != "sent,spooled" {
+		t.Errorf("got %q, want the spooled batch replayed after the successful send", messages)
+	}
+	if files := spooled(t, spool); len(files) != 0 {
+		t.Errorf("replayed batches left in the spool: %q", files)
+	}
+}
+
+func TestWebhookRejectedSpooled(t *testing.T) {
+	ws := newWebhookServer(t, func(request int) int {
+		if request == 1 {
+			return http.StatusUnauthorized
+		}
+		return http.StatusOK
+	})
+	s, spool := newTestWebhook(t, ws.URL, nil)
+
+	s.Write(types.LogEntry{Level: types.Error, Message: "rejected"})
+	if err := s.Flush(); err == nil {
+		t.Fatal("Flush succeeded while the server refused the batch")
+	}
+	if requests, _ := ws.received(); requests != 1 {
+		t.Errorf("got %d requests, want no retry after 401", requests)
+	}
+
+	s.Write(types.LogEntry{Level: types.Info, Message: "sent"})
+	if err := s.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, messages := ws.received(); strings.Join(messages, ",") != "sent" {
+		t.Errorf("got %q, want the rejected batch left out of the replay", messages)
+	}
+
+	files := spooled(t, spool)
+	if len(files) != 1 || !strings.HasSuffix(files[0], spoolExt+rejectedExt) {
+		t.Fatalf("spooled %q, want one %s batch", files, spoolExt+rejectedExt)
+	}
+}
+
+func TestWebhookDropsOverLimit(t *testing.T) {
+	release := make(chan struct{})
+	ws := newWebhookServer(t, func(int) int { return http.StatusOK })
+	handler := ws.Config.Handler
+	ws.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		handler.ServeHTTP(w, r)
+	})
+	defer close(release)
+
+	s, _ := newTestWebhook(t, ws.URL, func(cfg *types.Webhook) {
+		cfg.Batch = 1
+	})
+
+	s.Write(types.LogEntry{Level: types.Info, Message: "stuck"})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		s.batcher.mu.Lock()
+		waiting := len(s.batcher.pending)
+		s.batcher.mu.Unlock()
+
+		if waiting == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("first batch not sent")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	for i := 0; i < maxPendingBatches+10; i++ {
+		if err := s.Write(types.LogEntry{Level: types.Info, Message: "waiting"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s.batcher.mu.Lock()
+	waiting := len(s.batcher.pending)
+	s.batcher.mu.Unlock()
+
+	if waiting != maxPendingBatches {
+		t.Errorf("%d entries waiting, want %d", waiting, maxPendingBatches)
+	}
+	if s.Dropped() != 10 {
+		t.Errorf("dropped %d entries, want 10", s.Dropped())
+	}
+}
@@ -1,9 +1,14 @@
 package vloggo
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	config "github.com/vinialx/vloggo-go/config"
 	services "github.com/vinialx/vloggo-go/internal"
@@ -13,9 +18,30 @@ import (
 type VLoggo struct {
 	mu sync.Mutex
 
-	cfg    types.VLoggoConfig
-	file   *services.FileService
-	format *services.FormatService
+	cfg     types.VLoggoConfig
+	file    *services.FileService
+	txt     *services.TxtSink
+	json    *services.JSONSink
+	email   *services.EmailService
+	console *services.ConsoleService
+	async   *services.AsyncService
+	format  *services.FormatService
+
+	extra   []output
+	sinksMu sync.RWMutex
+
+	parent *VLoggo
+	fields types.Fields
+	closed atomic.Bool
+}
+
+// output is a sink of the instance built or taken from cfg.Outputs
+// built sinks come from a New factory, owned sinks are closed with the instance
+type output struct {
+	sink  types.Sink
+	level types.LogLevel
+	built bool
+	owned bool
 }
 
 var (
@@ -26,6 +52,7 @@ var (
 func NewInstance(client string, opts ...config.Option) *VLoggo {
 	mu.RLock()
 	if instance, exists := instances[client]; exists {
+		mu.RUnlock()
 		return instance
 	}
 
@@ -47,16 +74,54 @@ func NewInstance(client string, opts ...config.Option) *VLoggo {
 		}
 	}
 
+	instance := build(cfg, 0)
+	instances[client] = instance
+
+	return instance
+
+}
+
+// build creates an instance from cfg
+// The first shared outputs come from another instance, their sinks are written to but never closed
+func build(cfg types.VLoggoConfig, shared int) *VLoggo {
+	file := services.NewFileService(cfg)
+
 	instance := &VLoggo{
-		cfg:    cfg,
-		file:   services.NewFileService(cfg),
-		format: services.NewFormatService(cfg.Client),
+		cfg:     cfg,
+		file:    file,
+		txt:     services.NewTxtSink(file, cfg.Client),
+		json:    services.NewJSONSink(file, cfg.Client),
+		email:   services.NewEmailService(cfg),
+		console: services.NewConsoleService(cfg.Client),
+		format:  services.NewFormatService(cfg.Client),
+		extra: resolve(cfg, func(i int) bool {
+			return i >= shared
+		}),
 	}
 
-	instances[client] = instance
+	if cfg.Async.Enabled {
+		instance.async = services.NewAsyncService(cfg, instance.process)
+	}
 
 	return instance
+}
+
+// resolve builds the sinks of cfg.Outputs
+// Outputs with a New factory get a sink of their own, owned by the instance
+// Other sinks are used as is and owned when owned reports true for their index
+func resolve(cfg types.VLoggoConfig, owned func(i int) bool) []output {
+	outputs := make([]output, len(cfg.Outputs))
+
+	for i, o := range cfg.Outputs {
+		if o.New != nil {
+			outputs[i] = output{sink: o.New(cfg), level: o.Level, built: true, owned: true}
+			continue
+		}
+
+		outputs[i] = output{sink: o.Sink, level: o.Level, owned: owned(i)}
+	}
 
+	return outputs
 }
 
 func GetAllInstances() map[string]*VLoggo {
@@ -71,7 +136,19 @@ func GetAllInstances() map[string]*VLoggo {
 	return result
 }
 
+func (v *VLoggo) root() *VLoggo {
+	if v.parent != nil {
+		return v.parent
+	}
+
+	return v
+}
+
 func (v *VLoggo) GetConfig() types.VLoggoConfig {
+	if v.parent != nil {
+		return v.parent.GetConfig()
+	}
+
 	v.mu.Lock()
 	defer v.mu.Unlock()
 
@@ -80,29 +157,58 @@ func (v *VLoggo) GetConfig() types.VLoggoConfig {
 
 func RemoveInstance(client string) bool {
 	mu.Lock()
-	defer mu.Unlock()
+	instance, exists := instances[client]
+	delete(instances, client)
+	mu.Unlock()
 
-	if _, exists := instances[client]; exists {
-		delete(instances, client)
-		fmt.Printf("[VLoggo] > [%s] [%s] [INFO] : instance removed\n",
+	if !exists {
+		return false
+	}
+
+	if err := instance.Close(); err != nil {
+		fmt.Printf("[VLoggo] > [%s] [%s] [ERROR] : failed to close instance > %v\n",
 			client,
 			config.Date(),
+			err,
 		)
-		return true
 	}
-	return false
+
+	fmt.Printf("[VLoggo] > [%s] [%s] [INFO] : instance removed\n",
+		client,
+		config.Date(),
+	)
+	return true
 }
 
 func ClearInstances() {
-	mu.Lock()
-	defer mu.Unlock()
+	if err := Shutdown(context.Background()); err != nil {
+		fmt.Printf("[VLoggo] > [%s] [ERROR] : failed to close instances > %v\n",
+			config.Date(),
+			err,
+		)
+	}
 
-	instances = make(map[string]*VLoggo)
 	fmt.Printf("[VLoggo] > [%s] [INFO] : all instances removed \n",
 		config.Date(),
 	)
 }
 
+func Shutdown(ctx context.Context) error {
+	mu.Lock()
+	closing := instances
+	instances = make(map[string]*VLoggo)
+	mu.Unlock()
+
+	var errs []error
+	for client, instance := range closing {
+		if err := instance.close(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s > %w", client, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
 func Clone(base, client string, opts ...config.Option) *VLoggo {
 	mu.RLock()
 	baseInstance, exists := instances[base]
@@ -129,6 +235,7 @@ func Clone(base, client string, opts ...config.Option) *VLoggo {
 
 	cloneCfg := baseInstance.GetConfig()
 	cloneCfg.Client = client
+	shared := len(cloneCfg.Outputs)
 
 	for _, opt := range opts {
 		if opt != nil {
@@ -143,9 +250,7 @@ func Clone(base, client string, opts ...config.Option) *VLoggo {
 		return existingInstance
 	}
 
-	newInstance := &VLoggo{
-		cfg: cloneCfg,
-	}
+	newInstance := build(cloneCfg, shared)
 	instances[client] = newInstance
 
 	fmt.Printf("[VLoggo] > [%s] [%s] [INFO] : instance cloned from %s\n",
@@ -158,67 +263,312 @@ func Clone(base, client string, opts ...config.Option) *VLoggo {
 }
 
 func (v *VLoggo) Update(opts ...config.Option) {
+	if v.parent != nil {
+		v.parent.Update(opts...)
+		return
+	}
+
 	v.mu.Lock()
-	defer v.mu.Unlock()
+	previous := v.cfg.Outputs
 
 	for _, opt := range opts {
 		if opt != nil {
 			opt(&v.cfg)
 		}
 	}
+
+	v.email.Update(v.cfg)
+
+	client := v.cfg.Client
+	var stale []types.Sink
+	if changed(previous, v.cfg.Outputs) {
+		stale = v.rebuild(v.cfg)
+	}
+	v.mu.Unlock()
+
+	for _, sink := range stale {
+		if err := sink.Close(); err != nil {
+			fmt.Printf("[VLoggo] > [%s] [%s] [ERROR] : failed to close sink > %v\n",
+				client,
+				v.format.Date(),
+				err,
+			)
+		}
+	}
+}
+
+// changed reports whether an option replaced the outputs of a config
+// Options never modify Outputs in place, they always set a new slice
+func changed(previous, outputs []types.Output) bool {
+	if len(previous) != len(outputs) {
+		return true
+	}
+
+	return len(outputs) > 0 && &previous[0] != &outputs[0]
+}
+
+// rebuild replaces the sinks of the instance by new ones built from cfg.Outputs
+// Sinks not built from a factory keep their place and ownership, since options only append or replace built ones
+// Returns the owned sinks no longer used, for the caller to close
+// Must be called with v.mu held
+func (v *VLoggo) rebuild(cfg types.VLoggoConfig) []types.Sink {
+	v.sinksMu.Lock()
+	previous := v.extra
+	v.extra = resolve(cfg, func(i int) bool {
+		return i >= len(previous) || previous[i].owned
+	})
+	outputs := v.extra
+	v.sinksMu.Unlock()
+
+	var stale []types.Sink
+	for i, o := range previous {
+		kept := !o.built && i < len(outputs) && !outputs[i].built
+		if o.owned && !kept {
+			stale = append(stale, o.sink)
+		}
+	}
+
+	return stale
+}
+
+func (v *VLoggo) With(fields ...any) *VLoggo {
+	bound := make(types.Fields, 0, len(v.fields)+len(fields))
+	bound = append(bound, v.fields...)
+	bound = append(bound, services.Fields(fields...)...)
+
+	return &VLoggo{
+		file:    v.file,
+		txt:     v.txt,
+		json:    v.json,
+		email:   v.email,
+		console: v.console,
+		async:   v.async,
+		format:  v.format,
+		parent:  v.root(),
+		fields:  bound,
+	}
 }
 
-func (v *VLoggo) log(level types.LogLevel, code, message string) {
+func (v *VLoggo) OnRotate(hook func(oldPath, newPath string) error) {
+	v.file.OnRotate(hook)
+}
 
-	entry := types.LogEntry{
+func (v *VLoggo) SetConsoleOutput(stdout, stderr io.Writer) {
+	v.console.SetOutput(stdout, stderr)
+}
+
+func (v *VLoggo) log(level types.LogLevel, code, message string, fields ...any) {
+	if !v.enabled(level) {
+		return
+	}
+
+	v.write(types.LogEntry{
 		Level:   level,
 		Code:    code,
-		Caller:  services.Caller(3),
+		Caller:  services.Caller(2),
 		Message: message,
+		Fields:  append(v.fields[:len(v.fields):len(v.fields)], services.Fields(fields...)...),
+		Time:    time.Now(),
+	})
+}
+
+func (v *VLoggo) enabled(level types.LogLevel) bool {
+	return level.Severity() >= v.GetConfig().MinLevel.Severity()
+}
+
+func (v *VLoggo) write(entry types.LogEntry) {
+	if v.root().closed.Load() {
+		return
 	}
 
-	line := v.format.Line(entry)
+	if v.async != nil {
+		v.async.Push(entry)
+		return
+	}
 
-	if v.cfg.Json {
-		jsonLine := v.format.JSONLine(entry)
+	v.process(entry)
+}
 
-		if err := v.file.Write(line, jsonLine); err != nil {
-			fmt.Printf("[VLoggo] > [%s] [%s] [INFO] : failed to write to log file > %s\n",
-				v.cfg.Client,
-				v.format.Date(),
-				err,
-			)
+// outputs returns every sink entries are written to, with its level
+// Must be called with the sinksMu of the root instance held
+func (v *VLoggo) outputs(cfg types.VLoggoConfig) []output {
+	root := v.root()
+	outputs := make([]output, 0, 3+len(root.extra))
+
+	if cfg.Console {
+		outputs = append(outputs, output{sink: v.console, level: cfg.Levels.Console})
+	}
+
+	outputs = append(outputs, output{sink: v.txt, level: cfg.Levels.Txt})
+
+	if v.file.JSON() {
+		outputs = append(outputs, output{sink: v.json, level: cfg.Levels.Json})
+	}
+
+	return append(outputs, root.extra...)
+}
+
+// sinks returns the sinks of the instance, only those it owns when owned is set
+func (v *VLoggo) sinks(owned bool) []types.Sink {
+	root := v.root()
+	root.sinksMu.RLock()
+	defer root.sinksMu.RUnlock()
+
+	sinks := []types.Sink{v.console, v.txt, v.json}
+	for _, o := range root.extra {
+		if !owned || o.owned {
+			sinks = append(sinks, o.sink)
 		}
-	} else {
-		if err := v.file.Write(line); err != nil {
-			fmt.Printf("[VLoggo] > [%s] [%s] [INFO] : failed to write to log file > %s\n",
-				v.cfg.Client,
+	}
+
+	return sinks
+}
+
+func (v *VLoggo) process(entry types.LogEntry) {
+	cfg := v.GetConfig()
+
+	root := v.root()
+	root.sinksMu.RLock()
+	for _, output := range v.outputs(cfg) {
+		if output.sink == nil || entry.Level.Severity() < output.level.Severity() {
+			continue
+		}
+
+		if err := output.sink.Write(entry); err != nil {
+			fmt.Printf("[VLoggo] > [%s] [%s] [ERROR] : failed to write to sink > %v\n",
+				cfg.Client,
 				v.format.Date(),
 				err,
 			)
 		}
 	}
+	root.sinksMu.RUnlock()
+
+	v.email.Notify(entry)
+}
+
+func (v *VLoggo) Flush(ctx context.Context) error {
+	if v.async != nil {
+		if err := v.async.Flush(ctx); err != nil {
+			return err
+		}
+	}
+
+	var errs []error
+	for _, sink := range v.sinks(false) {
+		if sink == nil {
+			continue
+		}
+		if err := sink.Flush(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Dropped returns the number of entries dropped by the async queue and by the sinks
+// that drop entries under backpressure, such as the webhook, Loki and Elasticsearch sinks
+func (v *VLoggo) Dropped() uint64 {
+	var dropped uint64
+	if v.async != nil {
+		dropped = v.async.Dropped()
+	}
+
+	for _, sink := range v.sinks(false) {
+		if d, ok := sink.(interface{ Dropped() uint64 }); ok {
+			dropped += d.Dropped()
+		}
+	}
+
+	return dropped
+}
+
+func (v *VLoggo) Close() error {
+	return v.close(context.Background())
+}
+
+func (v *VLoggo) close(ctx context.Context) error {
+	if v.parent != nil {
+		return nil
+	}
+
+	if v.closed.Swap(true) {
+		return nil
+	}
+
+	var errs []error
+
+	if v.async != nil {
+		if err := v.async.Close(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("error draining async queue > %w", err))
+		}
+	}
+
+	// Sinks are closed and pending emails sent side by side, each step racing ctx
+	// so a slow sink cannot hold up the emails or get them blamed for the timeout
+	closed := make(chan error, 1)
+	go func() {
+		var errs []error
+		for _, sink := range v.sinks(true) {
+			if sink == nil {
+				continue
+			}
+			if err := sink.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		closed <- errors.Join(errs...)
+	}()
+
+	sent := make(chan struct{})
+	go func() {
+		v.email.Flush()
+		close(sent)
+	}()
+
+	select {
+	case err := <-closed:
+		if err != nil {
+			errs = append(errs, err)
+		}
+	case <-ctx.Done():
+		errs = append(errs, fmt.Errorf("error closing sinks > %w", ctx.Err()))
+	}
+
+	select {
+	case <-sent:
+	case <-ctx.Done():
+		select {
+		case <-sent:
+		default:
+			errs = append(errs, fmt.Errorf("error sending pending emails > %w", ctx.Err()))
+		}
+	}
 
+	return errors.Join(errs...)
 }
 
-func (v *VLoggo) Info(code, message string) {
-	v.log("INFO", code, message)
+func (v *VLoggo) Info(code, message string, fields ...any) {
+	v.log("INFO", code, message, fields...)
 }
 
-func (v *VLoggo) Warn(code, message string) {
-	v.log("WARN", code, message)
+func (v *VLoggo) Warn(code, message string, fields ...any) {
+	v.log("WARN", code, message, fields...)
 }
 
-func (v *VLoggo) Debug(code, message string) {
-	v.log("DEBUG", code, message)
+func (v *VLoggo) Debug(code, message string, fields ...any) {
+	v.log("DEBUG", code, message, fields...)
 }
 
-func (v *VLoggo) Error(code, message string) {
-	v.log("ERROR", code, message)
+func (v *VLoggo) Error(code, message string, fields ...any) {
+	v.log("ERROR", code, message, fields...)
 }
 
-func (v *VLoggo) Fatal(code, message string) {
-	v.log("FATAL", code, message)
+func (v *VLoggo) Fatal(code, message string, fields ...any) {
+	v.log("FATAL", code, message, fields...)
 
+	v.Flush(context.Background())
+	v.email.Flush()
 	os.Exit(1)
 }
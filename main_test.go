@@ -0,0 +1,367 @@
+package vloggo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	config "github.com/vinialx/vloggo-go/config"
+	types "github.com/vinialx/vloggo-go/types"
+)
+
+// recordSink records the entries written to it and fails once closed
+type recordSink struct {
+	mu      sync.Mutex
+	entries []types.LogEntry
+	closed  bool
+}
+
+func (s *recordSink) Write(entry types.LogEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return errors.New("sink is closed")
+	}
+	s.entries = append(s.entries, entry)
+	return nil
+}
+
+func (s *recordSink) Flush() error {
+	return nil
+}
+
+func (s *recordSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	return nil
+}
+
+func (s *recordSink) state() (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.entries), s.closed
+}
+
+// testOptions keeps an instance out of the home directory and off the console
+func testOptions(t *testing.T) []config.Option {
+	dir := t.TempDir()
+	return []config.Option{
+		config.WithDirectory(types.VLoggoConfig{}, types.Paths{Txt: dir, Json: dir + "/json"}),
+		config.WithConsole(types.VLoggoConfig{}, false),
+	}
+}
+
+func TestCloneOwnsItsSinks(t *testing.T) {
+	shared := &recordSink{}
+	var mu sync.Mutex
+	built := map[string]*recordSink{}
+
+	opts := append(testOptions(t),
+		config.WithSink(types.VLoggoConfig{}, shared, types.Debug),
+		func(cfg *types.VLoggoConfig) {
+			cfg.Outputs = append(cfg.Outputs[:len(cfg.Outputs):len(cfg.Outputs)], types.Output{
+				New: func(cfg types.VLoggoConfig) types.Sink {
+					mu.Lock()
+					defer mu.Unlock()
+
+					built[cfg.Client] = &recordSink{}
+					return built[cfg.Client]
+				},
+				Level: types.Debug,
+			})
+		},
+	)
+
+	base := NewInstance("test-clone-base", opts...)
+	t.Cleanup(func() { RemoveInstance("test-clone-base") })
+
+	if Clone("test-clone-base", "test-clone") == nil {
+		t.Fatal("Clone returned nil")
+	}
+	if built["test-clone-base"] == nil || built["test-clone"] == nil || built["test-clone-base"] == built["test-clone"] {
+		t.Fatal("clone did not build a sink of its own")
+	}
+
+	RemoveInstance("test-clone")
+
+	if _, closed := shared.state(); closed {
+		t.Fatal("removing the clone closed a sink of the base")
+	}
+	if _, closed := built["test-clone-base"].state(); closed {
+		t.Fatal("removing the clone closed the sink built for the base")
+	}
+	if _, closed := built["test-clone"].state(); !closed {
+		t.Fatal("removing the clone left its own sink open")
+	}
+
+	base.Info("TEST", "after clone removal")
+	if n, _ := shared.state(); n != 1 {
+		t.Fatalf("shared sink got %d entries, want 1", n)
+	}
+
+	RemoveInstance("test-clone-base")
+	if _, closed := shared.state(); !closed {
+		t.Fatal("removing the base left its sink open")
+	}
+}
+
+func TestUpdateReplacesSink(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	syslog := types.Syslog{Network: "udp", Address: conn.LocalAddr().String()}
+
+	v := NewInstance("test-update-sink", testOptions(t)...)
+	t.Cleanup(func() { RemoveInstance("test-update-sink") })
+
+	v.Update(config.WithSyslog(types.VLoggoConfig{}, syslog))
+	v.Update(config.WithSyslog(types.VLoggoConfig{}, syslog, types.Warn))
+
+	if n := len(v.GetConfig().Outputs); n != 1 {
+		t.Fatalf("config has %d outputs, want 1", n)
+	}
+
+	v.Info("TEST", "below the new level")
+	v.Warn("TEST", "at the new level")
+
+	buf := make([]byte, 4096)
+	var got []string
+	for {
+		conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			break
+		}
+		got = append(got, string(buf[:n]))
+	}
+
+	if len(got) != 1 {
+		t.Fatalf("got %d syslog messages, want 1 from the replacing sink: %q", len(got), got)
+	}
+}
+
+func TestUpdateJSONKeepsFileSetting(t *testing.T) {
+	v := NewInstance("test-update-json", testOptions(t)...)
+	t.Cleanup(func() { RemoveInstance("test-update-json") })
+
+	v.Update(config.WithJSON(types.VLoggoConfig{}, true))
+
+	v.sinksMu.RLock()
+	defer v.sinksMu.RUnlock()
+
+	for _, o := range v.outputs(v.GetConfig()) {
+		if o.sink == v.json {
+			t.Fatal("json sink written to although the file service has no json file")
+		}
+	}
+}
+
+// slowSink is a recordSink whose Close blocks until release is closed
+type slowSink struct {
+	recordSink
+	release chan struct{}
+}
+
+func (s *slowSink) Close() error {
+	<-s.release
+	return s.recordSink.Close()
+}
+
+func TestCloseIsFinal(t *testing.T) {
+	sink := &recordSink{}
+	v := NewInstance("test-close", append(testOptions(t),
+		config.WithSink(types.VLoggoConfig{}, sink, types.Debug),
+	)...)
+	t.Cleanup(func() { RemoveInstance("test-close") })
+
+	v.Info("TEST", "before close")
+
+	if err := v.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, closed := sink.state(); !closed {
+		t.Fatal("Close left the sink open")
+	}
+
+	v.Info("TEST", "after close")
+	v.With("key", "value").Error("TEST", "after close")
+	if n, _ := sink.state(); n != 1 {
+		t.Fatalf("sink got %d entries, want only the one logged before Close", n)
+	}
+
+	if err := v.Close(); err != nil {
+		t.Fatalf("second Close returned %v", err)
+	}
+}
+
+func TestCloseStopsAtDeadline(t *testing.T) {
+	sink := &slowSink{release: make(chan struct{})}
+	defer close(sink.release)
+
+	v := NewInstance("test-close-deadline", append(testOptions(t),
+		config.WithSink(types.VLoggoConfig{}, sink, types.Debug),
+	)...)
+	mu.Lock()
+	delete(instances, "test-close-deadline")
+	mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := v.close(ctx)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("close took %v with a 50ms deadline", elapsed)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "error closing sinks") {
+		t.Fatalf("close returned %v, want the sinks step to time out", err)
+	}
+	if strings.Contains(err.Error(), "pending emails") {
+		t.Fatalf("close blamed the email flush: %v", err)
+	}
+}
+
+func TestRemoveInstanceFlushesFile(t *testing.T) {
+	dir := t.TempDir()
+	sink := &recordSink{}
+	v := NewInstance("test-remove",
+		config.WithDirectory(types.VLoggoConfig{}, types.Paths{Txt: dir, Json: dir + "/json"}),
+		config.WithConsole(types.VLoggoConfig{}, false),
+		config.WithSink(types.VLoggoConfig{}, sink, types.Debug),
+	)
+
+	v.Info("TEST", "flushed on remove")
+
+	if !RemoveInstance("test-remove") {
+		t.Fatal("RemoveInstance did not find the instance")
+	}
+	if RemoveInstance("test-remove") {
+		t.Fatal("instance still registered after RemoveInstance")
+	}
+	if _, closed := sink.state(); !closed {
+		t.Fatal("RemoveInstance left the sink open")
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.txt"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("found log files %v (%v), want 1", files, err)
+	}
+
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "flushed on remove") {
+		t.Fatalf("log file does not contain the entry: %q", data)
+	}
+}
+
+func TestWithWriterLevel(t *testing.T) {
+	var buf bytes.Buffer
+	v := NewInstance("test-writer", append(testOptions(t),
+		config.WithWriter(types.VLoggoConfig{}, &buf, types.Text, types.Warn),
+	)...)
+	t.Cleanup(func() { RemoveInstance("test-writer") })
+
+	v.Info("TEST", "below the writer level")
+	v.Error("TEST", "at or above the writer level")
+
+	if strings.Contains(buf.String(), "below the writer level") {
+		t.Error("writer got an entry below its level")
+	}
+	if !strings.Contains(buf.String(), "[test-writer]") || !strings.Contains(buf.String(), "[ERROR] [TEST]") {
+		t.Errorf("writer missed the ERROR entry: %q", buf.String())
+	}
+}
+
+func TestMinLevel(t *testing.T) {
+	sink := &recordSink{}
+	v := NewInstance("test-min-level", append(testOptions(t),
+		config.WithSink(types.VLoggoConfig{}, sink, types.Debug),
+		config.WithLevel(types.VLoggoConfig{}, types.Warn),
+	)...)
+	t.Cleanup(func() { RemoveInstance("test-min-level") })
+
+	v.Debug("TEST", "below the minimum level")
+	v.Info("TEST", "below the minimum level")
+	v.Warn("TEST", "at the minimum level")
+	if n, _ := sink.state(); n != 1 {
+		t.Fatalf("sink got %d entries, want only the WARN one", n)
+	}
+
+	v.Update(config.WithLevel(types.VLoggoConfig{}, types.Debug))
+
+	v.Debug("TEST", "allowed after Update")
+	v.With("key", "value").Info("TEST", "allowed after Update")
+	if n, _ := sink.state(); n != 3 {
+		t.Fatalf("sink got %d entries after lowering the minimum level, want 3", n)
+	}
+}
+
+func TestWithBindsFields(t *testing.T) {
+	dir := t.TempDir()
+	sink := &recordSink{}
+	v := NewInstance("test-with",
+		config.WithDirectory(types.VLoggoConfig{}, types.Paths{Txt: dir, Json: dir + "/json"}),
+		config.WithConsole(types.VLoggoConfig{}, false),
+		config.WithSink(types.VLoggoConfig{}, sink, types.Debug),
+	)
+	t.Cleanup(func() { RemoveInstance("test-with") })
+
+	before, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mu.RLock()
+	registered := len(instances)
+	mu.RUnlock()
+
+	child := v.With("request", "r-1")
+	grandchild := child.With("user", "ana")
+
+	after, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(after) != len(before) {
+		t.Errorf("With opened files, the directory went from %d to %d entries", len(before), len(after))
+	}
+	mu.RLock()
+	if len(instances) != registered {
+		t.Errorf("With registered an instance, %d registered instead of %d", len(instances), registered)
+	}
+	mu.RUnlock()
+	if child.file != v.file || grandchild.root() != v {
+		t.Error("child loggers do not share the parent file service")
+	}
+
+	grandchild.Info("TEST", "bound", "extra", 1)
+	v.Info("TEST", "unbound")
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+
+	if len(sink.entries) != 2 {
+		t.Fatalf("sink got %d entries, want 2", len(sink.entries))
+	}
+	if got := sink.entries[0].Fields; len(got) != 3 || got[0].Key != "request" || got[1].Key != "user" || got[2].Key != "extra" {
+		t.Errorf("child entry fields = %v, want request, user and extra", got)
+	}
+	if got := sink.entries[1].Fields; len(got) != 0 {
+		t.Errorf("parent entry fields = %v, want none", got)
+	}
+}
@@ -0,0 +1,146 @@
+package vloggo
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	services "github.com/vinialx/vloggo-go/internal"
+	types "github.com/vinialx/vloggo-go/types"
+)
+
+// CodeKey is the attribute key whose value is used as the entry Code by Handler.
+const CodeKey = "code"
+
+// DefaultCode is the Code used by Handler when a record has no CodeKey attribute.
+const DefaultCode = "SLOG"
+
+// Handler is a slog.Handler that writes records through a VLoggo instance.
+// Attributes and groups become structured fields, with group names joined by dots.
+// Every VLoggo line carries a timestamp, so a record with a zero Time is written with the time
+// it is handled instead of without one. This is the only check of testing/slogtest it does not pass.
+type Handler struct {
+	logger *VLoggo
+	fields types.Fields
+	code   string
+	group  string
+}
+
+// NewHandler returns a slog.Handler backed by v.
+func NewHandler(v *VLoggo) *Handler {
+	return &Handler{logger: v}
+}
+
+// slogLevel maps a slog.Level to the matching types.LogLevel.
+// Levels at or above slog.LevelError+4 are mapped to FATAL, without exiting.
+func slogLevel(level slog.Level) types.LogLevel {
+	switch {
+	case level < slog.LevelInfo:
+		return types.Debug
+	case level < slog.LevelWarn:
+		return types.Info
+	case level < slog.LevelError:
+		return types.Warn
+	case level < slog.LevelError+4:
+		return types.Error
+	default:
+		return types.Fatal
+	}
+}
+
+func (h *Handler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.logger.enabled(slogLevel(level))
+}
+
+func (h *Handler) Handle(_ context.Context, r slog.Record) error {
+	code := h.code
+	fields := make(types.Fields, 0, len(h.fields)+r.NumAttrs())
+	fields = append(fields, h.fields...)
+
+	r.Attrs(func(a slog.Attr) bool {
+		if h.group == "" && a.Key == CodeKey {
+			code = a.Value.Resolve().String()
+			return true
+		}
+		fields = appendAttr(fields, h.group, a)
+		return true
+	})
+
+	if code == "" {
+		code = DefaultCode
+	}
+
+	t := r.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+
+	h.logger.write(types.LogEntry{
+		Level:   slogLevel(r.Level),
+		Code:    code,
+		Caller:  services.CallerFromPC(r.PC),
+		Message: r.Message,
+		Fields:  append(h.logger.fields[:len(h.logger.fields):len(h.logger.fields)], fields...),
+		Time:    t,
+	})
+
+	return nil
+}
+
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+
+	child := *h
+	child.fields = h.fields[:len(h.fields):len(h.fields)]
+
+	for _, a := range attrs {
+		if h.group == "" && a.Key == CodeKey {
+			child.code = a.Value.Resolve().String()
+			continue
+		}
+		child.fields = appendAttr(child.fields, h.group, a)
+	}
+
+	return &child
+}
+
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	child := *h
+	child.group = joinKey(h.group, name)
+
+	return &child
+}
+
+// appendAttr appends a resolved attribute to fields, flattening groups into dotted keys.
+func appendAttr(fields types.Fields, prefix string, a slog.Attr) types.Fields {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return fields
+	}
+
+	if a.Value.Kind() == slog.KindGroup {
+		for _, ga := range a.Value.Group() {
+			fields = appendAttr(fields, joinKey(prefix, a.Key), ga)
+		}
+		return fields
+	}
+
+	return append(fields, types.Field{Key: joinKey(prefix, a.Key), Value: a.Value.Any()})
+}
+
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	if key == "" {
+		return prefix
+	}
+	return strings.Join([]string{prefix, key}, ".")
+}
@@ -0,0 +1,123 @@
+package vloggo
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+	"testing/slogtest"
+
+	config "github.com/vinialx/vloggo-go/config"
+	types "github.com/vinialx/vloggo-go/types"
+)
+
+// slogInstance creates an instance writing JSON lines to the returned buffer
+func slogInstance(t *testing.T, client string) (*VLoggo, *bytes.Buffer) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	v := NewInstance(client, append(testOptions(t),
+		config.WithWriter(types.VLoggoConfig{}, &buf, types.JSON),
+	)...)
+	t.Cleanup(func() { RemoveInstance(client) })
+
+	return v, &buf
+}
+
+// slogLine decodes the single JSON line written to buf
+func slogLine(t *testing.T, buf *bytes.Buffer) map[string]any {
+	t.Helper()
+
+	var line map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("output is not a single JSON line > %v: %q", err, buf.String())
+	}
+
+	return line
+}
+
+func TestHandlerSlogtest(t *testing.T) {
+	var buf *bytes.Buffer
+
+	slogtest.Run(t, func(t *testing.T) slog.Handler {
+		// See Handler, records with a zero Time are written with the current time
+		if strings.HasSuffix(t.Name(), "/zero-time") {
+			t.Skip("Handler writes the current time for a zero Record.Time")
+		}
+
+		var v *VLoggo
+		v, buf = slogInstance(t, "test-slogtest")
+		return NewHandler(v)
+	}, func(t *testing.T) map[string]any {
+		line := slogLine(t, buf)
+
+		result := map[string]any{
+			slog.TimeKey:    line["timestamp"],
+			slog.LevelKey:   line["level"],
+			slog.MessageKey: line["message"],
+		}
+
+		fields, _ := line["fields"].(map[string]any)
+		for key, value := range fields {
+			group := result
+			path := strings.Split(key, ".")
+			for _, name := range path[:len(path)-1] {
+				if _, ok := group[name].(map[string]any); !ok {
+					group[name] = map[string]any{}
+				}
+				group = group[name].(map[string]any)
+			}
+			group[path[len(path)-1]] = value
+		}
+
+		return result
+	})
+}
+
+func TestHandlerCodeAndCaller(t *testing.T) {
+	v, buf := slogInstance(t, "test-slog-code")
+	logger := slog.New(NewHandler(v))
+
+	logger.Warn("disk almost full", CodeKey, "DISK", "free", "2%")
+
+	line := slogLine(t, buf)
+	if line["code"] != "DISK" || line["level"] != "WARN" {
+		t.Errorf("got code %v and level %v, want DISK and WARN", line["code"], line["level"])
+	}
+	if fields, _ := line["fields"].(map[string]any); fields["code"] != nil || fields["free"] != "2%" {
+		t.Errorf("got fields %v, want only free", line["fields"])
+	}
+	if caller, _ := line["caller"].(string); !strings.HasPrefix(caller, "slog_test.go:") {
+		t.Errorf("got caller %q, want the slog_test.go call site", caller)
+	}
+
+	buf.Reset()
+	logger.Info("no code")
+	if line := slogLine(t, buf); line["code"] != DefaultCode {
+		t.Errorf("got code %v, want %s", line["code"], DefaultCode)
+	}
+}
+
+func TestHandlerGroupKeys(t *testing.T) {
+	v, buf := slogInstance(t, "test-slog-group")
+	logger := slog.New(NewHandler(v)).WithGroup("req").With("id", "42")
+
+	logger.Info("handled", "path", "/users", CodeKey, "HTTP", slog.Group("user", "name", "ana"))
+
+	line := slogLine(t, buf)
+	if line["code"] != DefaultCode {
+		t.Errorf("code attr inside a group set the code to %v", line["code"])
+	}
+
+	want := map[string]any{"req.id": "42", "req.path": "/users", "req.code": "HTTP", "req.user.name": "ana"}
+	fields, _ := line["fields"].(map[string]any)
+	if len(fields) != len(want) {
+		t.Fatalf("got fields %v, want %v", fields, want)
+	}
+	for key, value := range want {
+		if fields[key] != value {
+			t.Errorf("field %s = %v, want %v", key, fields[key], value)
+		}
+	}
+}
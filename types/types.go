@@ -1,15 +1,155 @@
 package types
 
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
 type Paths struct {
 	Txt  string
 	Json string
 }
 
+// Sink is an output that receives every log entry of an instance at or above its level
+// Each sink does its own formatting. Close must be safe to call more than once
+type Sink interface {
+	Write(entry LogEntry) error
+	Flush() error
+	Close() error
+}
+
+// Output is an extra sink of an instance with its minimum level
+// New builds a sink of its own for every instance created from the config, so clones never share it
+// Sink is used as is when New is nil, and only the instance it was added to closes it
+// Key names the destination of the sink, adding an output with the same Key replaces the previous one
+type Output struct {
+	Key   string
+	New   func(cfg VLoggoConfig) Sink
+	Sink  Sink
+	Level LogLevel
+}
+
+type Levels struct {
+	Console LogLevel
+	Txt     LogLevel
+	Json    LogLevel
+}
+
+type Templates struct {
+	Txt     string
+	Json    string
+	Current bool
+}
+
 type Count struct {
 	Txt  int
 	Json int
 }
 
+const (
+	Hourly = time.Hour
+	Daily  = 24 * time.Hour
+	Weekly = 7 * Daily
+)
+
+type Rollover struct {
+	Interval time.Duration
+	Location *time.Location
+	Timer    bool
+}
+
+type Retention struct {
+	MaxAge        time.Duration
+	MaxTotalBytes int64
+}
+
+type Flush struct {
+	Size     int
+	Interval time.Duration
+	Level    LogLevel
+}
+
+type Format string
+
+const (
+	Text Format = "TEXT"
+	JSON Format = "JSON"
+)
+
+type Overflow string
+
+const (
+	Block      Overflow = "BLOCK"
+	DropNewest Overflow = "DROP_NEWEST"
+	DropOldest Overflow = "DROP_OLDEST"
+)
+
+type Async struct {
+	Enabled  bool
+	Queue    int
+	Overflow Overflow
+}
+
+// Syslog configures a syslog sink
+// Network is "unix", "unixgram", "udp" or "tcp", an empty Network uses the local /dev/log socket
+// Facility is the syslog facility code (0-23), 0 (kern) is replaced by 1 (user)
+type Syslog struct {
+	Network  string
+	Address  string
+	Facility int
+}
+
+// Webhook configures an HTTP webhook sink
+// Zero values use the defaults: batches of 100 entries, sent every 5 seconds,
+// 3 retries starting at 1 second, a 10 second timeout and spooling under Directory.Json
+// A negative Retries disables retries. At most 100 batches wait to be sent, later entries are dropped
+type Webhook struct {
+	URL      string
+	Headers  map[string]string
+	Batch    int
+	Interval time.Duration
+	Gzip     bool
+	Retries  int
+	Backoff  time.Duration
+	Timeout  time.Duration
+	Spool    string
+}
+
+// Loki configures a Grafana Loki push sink
+// URL is the Loki base URL, the /loki/api/v1/push path is added when missing
+// Labels are added to the client and level stream labels, Headers to every request (like X-Scope-OrgID)
+// Zero values use the same defaults as Webhook
+type Loki struct {
+	URL      string
+	Labels   map[string]string
+	Headers  map[string]string
+	Batch    int
+	Interval time.Duration
+	Retries  int
+	Backoff  time.Duration
+	Timeout  time.Duration
+}
+
+// Elastic configures an Elasticsearch/OpenSearch bulk sink
+// Entries are indexed into daily indices named Index-client-YYYY.MM.DD, Index defaulting to "vloggo"
+// Headers are added to every request (like Authorization)
+// Rejected documents are appended to DeadLetter, defaulting to a file in an elastic folder under Directory.Json
+// Zero values use the same defaults as Webhook
+type Elastic struct {
+	URL        string
+	Index      string
+	Headers    map[string]string
+	Batch      int
+	Interval   time.Duration
+	Gzip       bool
+	Retries    int
+	Backoff    time.Duration
+	Timeout    time.Duration
+	DeadLetter string
+}
+
 type VLoggoSMTP struct {
 	Host     string   `env:"SMTP_HOST"`
 	Port     int      `env:"SMTP_PORT"`
@@ -23,11 +163,22 @@ type VLoggoConfig struct {
 	Client    string
 	Json      bool
 	Notify    bool
+	Digest    bool
 	Debug     bool
 	Console   bool
+	MinLevel  LogLevel
+	Levels    Levels
 	Throttle  int
+	MaxSize   int64
+	Compress  bool
 	Filecount Count
+	Retention Retention
+	Rollover  Rollover
+	Flush     Flush
+	Async     Async
 	Directory Paths
+	Templates Templates
+	Outputs   []Output
 	SMTP      VLoggoSMTP
 }
 
@@ -41,9 +192,72 @@ const (
 	Debug LogLevel = "DEBUG"
 )
 
+// Severity returns the numeric severity of the level, from DEBUG (0) to FATAL (4)
+// Unknown levels are treated as DEBUG
+func (l LogLevel) Severity() int {
+	switch l {
+	case Info:
+		return 1
+	case Warn:
+		return 2
+	case Error:
+		return 3
+	case Fatal:
+		return 4
+	default:
+		return 0
+	}
+}
+
+// Field is a structured key-value pair attached to a log entry
+type Field struct {
+	Key   string
+	Value any
+}
+
+// Fields is an ordered list of fields, encoded in JSON as a single object
+type Fields []Field
+
+// MarshalJSON encodes the fields as a JSON object, keeping their order
+// Errors are encoded as their message and values that cannot be encoded as their fmt representation
+func (f Fields) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+
+	for i, field := range f {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		key, err := json.Marshal(field.Key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+
+		value := field.Value
+		if e, ok := value.(error); ok {
+			value = e.Error()
+		}
+
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			encoded, _ = json.Marshal(fmt.Sprint(value))
+		}
+		buf.Write(encoded)
+	}
+
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
 type LogEntry struct {
 	Level   LogLevel `json:"level"`
 	Code    string   `json:"code"`
 	Caller  string   `json:"caller"`
 	Message string   `json:"message"`
+	Fields  Fields   `json:"fields,omitempty"`
+
+	Time time.Time `json:"-"`
 }